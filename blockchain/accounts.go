@@ -0,0 +1,53 @@
+package blockchain
+
+// An index of the blocks every account appears in, maintained incrementally as blocks are
+// added, so that history queries don't have to walk the chain.
+type accountIndex struct {
+	blocks map[string][]int // the indices of the blocks holding a transaction of the account, ascending
+}
+
+// This method enables an index mapping every account to the blocks holding its transactions,
+// which makes TransactionsFor read only those blocks instead of walking the chain, for example
+// in a wallet backend. Like the balance index (see EnableUTXOIndex), it is built from the
+// existing chain, updated whenever a block is mined or added, and rebuilt when ReplaceChain,
+// AddBlock or TruncateAfter rewrite the chain. The index is not saved; a chain read back with
+// LoadFromFile or another loader starts without it, and the index must be enabled again.
+// Enabling an already enabled index has no effect.
+func (b *Blockchain) EnableAccountIndex() {
+	b.lock()
+	defer b.mu.Unlock()
+	if b.accounts != nil {
+		return
+	}
+	b.accounts = &accountIndex{}
+	b.rebuildIndex()
+}
+
+// This method records the accounts of the block at the given index. Every account is recorded
+// once per block, however many of its transactions the block holds. Nothing happens if the
+// index is nil.
+func (idx *accountIndex) add(index int, block Block) {
+	if idx == nil {
+		return
+	}
+	for _, tx := range block.transactions {
+		for _, account := range [...]string{tx.From, tx.To} {
+			indices := idx.blocks[account]
+			if len(indices) == 0 || indices[len(indices)-1] != index {
+				idx.blocks[account] = append(indices, index)
+			}
+		}
+	}
+}
+
+// This method returns a copy of the index that shares no state with it, or nil.
+func (idx *accountIndex) clone() *accountIndex {
+	if idx == nil {
+		return nil
+	}
+	blocks := make(map[string][]int, len(idx.blocks))
+	for account, indices := range idx.blocks {
+		blocks[account] = append([]int(nil), indices...)
+	}
+	return &accountIndex{blocks: blocks}
+}
@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// This function sends a transfer from alice in every hundredth block, and transfers between
+// other accounts in the rest, so that alice appears in 1% of the blocks.
+func rareAlice(i int) (string, string) {
+	if i%100 == 0 {
+		return "alice", fmt.Sprintf("account%d", i%7)
+	}
+	return fmt.Sprintf("account%d", i%7), fmt.Sprintf("account%d", (i+1)%7)
+}
+
+func TestAccountIndexMatchesScan(t *testing.T) {
+	b := longChain(t, 1000, rareAlice)
+	scanned := b.TransactionsFor("alice")
+	if len(scanned) != 10 {
+		t.Fatalf("TransactionsFor(alice) returned %d transactions, want 10", len(scanned))
+	}
+	b.EnableAccountIndex()
+	if indexed := b.TransactionsFor("alice"); !reflect.DeepEqual(indexed, scanned) {
+		t.Fatalf("indexed TransactionsFor(alice) = %v, want %v", indexed, scanned)
+	}
+	if err := b.TruncateAfter(500); err != nil {
+		t.Fatal(err)
+	}
+	if indexed := b.TransactionsFor("alice"); !reflect.DeepEqual(indexed, scanned[:5]) {
+		t.Fatalf("indexed TransactionsFor(alice) after TruncateAfter = %v, want %v", indexed, scanned[:5])
+	}
+}
+
+func BenchmarkTransactionsFor(b *testing.B) {
+	chain := longChain(b, 100000, rareAlice)
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			chain.TransactionsFor("alice")
+		}
+	})
+	chain.EnableAccountIndex()
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			chain.TransactionsFor("alice")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// The key of the genesis block data under which CreateBlockchainWithAllocations records the
+// opening balances. Genesis data passed to CreateBlockchainWithGenesis under this key is
+// treated as allocations too.
+const AllocationsKey = "allocations"
+
+// This function creates a new blockchain whose genesis block pre-allocates funds: every
+// account in allocations starts with the given balance, which BalanceOf and the other balance
+// computations count before any transaction. The allocations are stored in the genesis
+// block's data under AllocationsKey, so they are covered by the genesis hash and can't be
+// changed unnoticed. The difficulty is clamped like in CreateBlockchain.
+// Allocations to an empty account or CoinbaseAddress, and amounts that are negative, not
+// finite, too large, or have more than MoneyDecimals decimal places, leave the genesis block
+// invalid, which Validate reports; use NewBlockchainWithAllocations to have them reported as
+// an error instead.
+func CreateBlockchainWithAllocations(difficulty int, allocations map[string]float64) Blockchain {
+	recorded := make(map[string]interface{}, len(allocations))
+	for account, amount := range allocations {
+		recorded[account] = amount
+	}
+	genesisData := map[string]interface{}{AllocationsKey: recorded}
+	return createBlockchain(difficulty, genesisData, time.Now(), sha256.New)
+}
+
+// This function is like CreateBlockchainWithAllocations, but returns an error if the
+// difficulty is out of range like NewBlockchain, or if an allocation is invalid.
+func NewBlockchainWithAllocations(difficulty int, allocations map[string]float64) (Blockchain, error) {
+	if difficulty < 0 || difficulty > MaxDifficulty {
+		return Blockchain{}, fmt.Errorf("difficulty %d out of range [0, %d]", difficulty, MaxDifficulty)
+	}
+	for account, amount := range allocations {
+		if err := checkAllocation(account, amount); err != nil {
+			return Blockchain{}, err
+		}
+	}
+	return CreateBlockchainWithAllocations(difficulty, allocations), nil
+}
+
+// This function checks one opening balance like CreateBlockchainWithAllocations.
+func checkAllocation(account string, amount float64) error {
+	if account == "" || account == CoinbaseAddress {
+		return fmt.Errorf("invalid allocation: account %q is not allowed", account)
+	}
+	money, err := ToMoney(amount)
+	if err != nil {
+		return fmt.Errorf("invalid allocation for %q: %w", account, err)
+	}
+	if money < 0 {
+		return fmt.Errorf("invalid allocation for %q: amount %v is negative", account, amount)
+	}
+	return nil
+}
+
+// This function checks the allocations recorded in genesis data, if any, like
+// CreateBlockchainWithAllocations checks its arguments. The allocations must be an object
+// mapping every account to a number.
+func checkAllocations(genesisData map[string]interface{}) error {
+	value, ok := genesisData[AllocationsKey]
+	if !ok {
+		return nil
+	}
+	recorded, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid allocations: %T is not an object", value)
+	}
+	for account, amount := range recorded {
+		number, ok := amount.(float64)
+		if !ok {
+			return fmt.Errorf("invalid allocation for %q: %T is not a number", account, amount)
+		}
+		if err := checkAllocation(account, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// This method returns the opening balances recorded in the genesis block. The caller must
+// hold the lock.
+func (b *Blockchain) allocations() map[string]Money {
+	recorded, _ := b.genesisBlock.data[AllocationsKey].(map[string]interface{})
+	allocations := make(map[string]Money, len(recorded))
+	for account, amount := range recorded {
+		if amount, ok := amount.(float64); ok {
+			allocations[account] = moneyOf(amount)
+		}
+	}
+	return allocations
+}
@@ -0,0 +1,74 @@
+package blockchain
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGenesisAllocationsSurviveSaveAndLoad(t *testing.T) {
+	genesisData := map[string]interface{}{AllocationsKey: map[string]interface{}{"alice": 100}}
+	b := CreateBlockchainWithGenesis(1, genesisData, GenesisEpoch())
+	if got := b.BalanceOf("alice"); got != 100 {
+		t.Fatalf("BalanceOf(alice) = %v, want 100", got)
+	}
+	path := filepath.Join(t.TempDir(), "chain.json")
+	if err := b.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.BalanceOf("alice"); got != 100 {
+		t.Fatalf("BalanceOf(alice) after LoadFromFile = %v, want 100", got)
+	}
+}
+
+func TestInvalidGenesisAllocations(t *testing.T) {
+	for name, allocations := range map[string]interface{}{
+		"negative amount":  map[string]interface{}{"alice": -5},
+		"coinbase account": map[string]interface{}{CoinbaseAddress: 5},
+		"empty account":    map[string]interface{}{"": 5},
+		"string amount":    map[string]interface{}{"alice": "100"},
+		"not an object":    []interface{}{"alice"},
+	} {
+		genesisData := map[string]interface{}{AllocationsKey: allocations}
+		b := CreateBlockchainWithGenesis(1, genesisData, GenesisEpoch())
+		if b.IsValid() {
+			t.Errorf("%s: IsValid() = true", name)
+		}
+		if _, err := NewBlockchainWithGenesis(1, genesisData, GenesisEpoch()); err == nil {
+			t.Errorf("%s: NewBlockchainWithGenesis() returned no error", name)
+		}
+		diagnostics := b.Diagnose()
+		if len(diagnostics) != 1 || !slices.Equal(diagnostics[0].Problems, []Problem{ProblemAllocations}) {
+			t.Errorf("%s: Diagnose() = %v, want invalid allocations in the genesis block", name, diagnostics)
+		}
+	}
+}
+
+func TestNewBlockchainWithAllocations(t *testing.T) {
+	for name, allocations := range map[string]map[string]float64{
+		"negative amount":   {"alice": -5},
+		"coinbase account":  {CoinbaseAddress: 5},
+		"empty account":     {"": 5},
+		"infinite amount":   {"alice": math.Inf(1)},
+		"too many decimals": {"alice": 0.123456789},
+	} {
+		if _, err := NewBlockchainWithAllocations(1, allocations); err == nil {
+			t.Errorf("%s: NewBlockchainWithAllocations() returned no error", name)
+		}
+		if b := CreateBlockchainWithAllocations(1, allocations); b.IsValid() {
+			t.Errorf("%s: IsValid() = true", name)
+		}
+	}
+	b, err := NewBlockchainWithAllocations(1, map[string]float64{"alice": 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.BalanceOf("alice"); got != 100 {
+		t.Fatalf("BalanceOf(alice) = %v, want 100", got)
+	}
+}
@@ -25,104 +25,1363 @@ dependencies are correctly managed in the go.mod file.
 package blockchain
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // The "Block" is the basic component of any blockchain.
 type Block struct {
-	data         map[string]interface{} // transaction data
+	data         map[string]interface{} // arbitrary block data
+	transactions []Transaction          // the transactions recorded in this block
 	hash         string                 // cryptographic hash used as a unique identifier
 	previousHash string                 // a secure link to the previous block. This is the "chain" of the blockchain.
 	timestamp    time.Time              // creation time
 	pow          int                    // the amount of work to derive this block's hash
+	merkleRoot   string                 // the root of the Merkle tree over the block's transactions
+	dataJSON     []byte                 // the marshaled data, cached so hashing doesn't re-marshal it
+	difficulty   int                    // the difficulty this block was mined at
+	target       *big.Int               // when set, the threshold the hash was mined below instead of the difficulty
+	height       int                    // the position of the block in the chain; the genesis block is at height 0
+	mining       *miningSession         // while the block is being mined, what its Miner needs; nil otherwise
 }
 
-// This holds the blocks of our blockchain
+// This holds the blocks of our blockchain.
+// A *Blockchain is safe for concurrent use: mutations take an exclusive lock and reads take a
+// shared lock. Create blockchains with CreateBlockchain; the zero value is usable too, as an
+// empty blockchain with difficulty 0 whose genesis block is created on first use, but it must
+// not be shared between goroutines before its first method call.
+// A Blockchain value refers to its state, so copies of the value, such as d := c, are the
+// same chain: blocks added through one are seen by all. Use Clone for an independent copy.
+// The blocks are kept in a BlockStore, in memory by default (see CreateBlockchainWithStore).
 type Blockchain struct {
-	genesisBlock Block   // the very first block
-	chain        []Block // all other blocks
-	difficulty   int     // the amount of work required to mine a new block
+	*chainState // shared by copies of the Blockchain value; nil until first use
 }
 
-// This method calculates the cryptographic hash of a block based on its data, previous hash, and timestamp.
-// It uses the SHA-256 hashing algorithm to generate a unique hash value for each block.
-func (b Block) calculateHash() string {
-	data, _ := json.Marshal(b.data)
-	blockData := b.previousHash + string(data) + b.timestamp.String() + strconv.Itoa(b.pow)
-	blockHash := sha256.Sum256([]byte(blockData))
-	return fmt.Sprintf("%x", blockHash)
+// The state of a blockchain, which all copies of a Blockchain value share.
+type chainState struct {
+	mu sync.RWMutex // guards all fields below
+
+	genesisBlock Block      // the very first block
+	store        BlockStore // all blocks, starting with the genesis block
+	difficulty   int        // the amount of work required to mine a new block
+
+	targetBlockTime time.Duration    // when non-zero, the difficulty is retargeted after each block
+	retargetWindow  int              // how many recent blocks retargeting averages over; 0 or 1 means only the last
+	minDifficulty   int              // the lowest difficulty any block may be mined at
+	miningReward    float64          // the amount credited to the miner for each mined block
+	minerAddress    string           // the account that receives the mining reward
+	blockMined      []func(Block)    // callbacks invoked after each mined block
+	pending         []Transaction    // the mempool: queued transactions waiting to be mined
+	queuedAt        []time.Time      // when each pending transaction was queued, by position
+	mempoolTTL      time.Duration    // how long a transaction may wait in the mempool; 0 means forever
+	maxBlockTxs     int              // the maximum number of queued transactions per block; 0 means unlimited
+	sideBlocks      map[string]Block // blocks of side branches, keyed by hash
+	now             func() time.Time // the clock used to timestamp blocks; nil means time.Now
+	hasher          func() hash.Hash // the hash algorithm for block hashes; nil means SHA-256
+	target          *big.Int         // when set, new blocks are mined below this threshold instead of the difficulty
+	startNonce      int              // the proof of work value at which mining starts
+	maxClockSkew    time.Duration    // how far ahead of the local clock a block's timestamp may be
+	finalized       int              // the index of the last final block, which can no longer be replaced
+	strict          bool             // when set, zero amounts and self-transfers are rejected
+	utxo            *balanceIndex    // when set, the balance of every account, kept up to date
+	accounts        *accountIndex    // when set, the blocks every account appears in, kept up to date
+	txIDs           map[string]int   // the index of the block recording each user transaction, by ID; nil until needed
+	mining          Miner            // the strategy used to mine new blocks; nil means SequentialMiner
+
+	logger     func(event string, fields map[string]interface{})     // receives structured events; nil means none
+	txRejected []func(from, to string, amount float64, reason error) // callbacks invoked for each refused transaction
+	metrics    metricsState                                          // the bookkeeping behind Metrics
+}
+
+// This method takes the exclusive lock, initializing a zero-value blockchain first.
+func (b *Blockchain) lock() {
+	b.lazyInit()
+	b.mu.Lock()
+}
+
+// This method takes the shared lock, initializing a zero-value blockchain first.
+func (b *Blockchain) rlock() {
+	b.lazyInit()
+	b.mu.RLock()
+}
+
+// This method turns a zero-value blockchain into an empty one, so that no method panics on a
+// Blockchain{} created with a struct literal.
+func (b *Blockchain) lazyInit() {
+	if b.chainState == nil {
+		*b = CreateBlockchain(0)
+	}
+}
+
+// The default of how far ahead of the validating node's clock a block's timestamp may be.
+const DefaultMaxClockSkew = 2 * time.Minute
+
+// The reserved "from" party of coinbase transactions, which create the mining reward out of
+// thin air. User transactions are not allowed to use it as their sender.
+const CoinbaseAddress = "COINBASE"
+
+// This method returns the block's cryptographic hash.
+func (b Block) Hash() string {
+	return b.hash
+}
+
+// This method returns the hash of the block before this one in the chain.
+func (b Block) PreviousHash() string {
+	return b.previousHash
+}
+
+// This method returns the time the block was created.
+func (b Block) Timestamp() time.Time {
+	return b.timestamp
+}
+
+// This method returns the position of the block in the chain, where the genesis block is at
+// height 0. The height is part of the block's hash, so it can't be changed unnoticed.
+func (b Block) Height() int {
+	return b.height
+}
+
+// This method returns the difficulty the block was mined at. Validate checks every block
+// against its own difficulty, so blocks stay valid when the chain's difficulty changes.
+func (b Block) Difficulty() int {
+	return b.difficulty
+}
+
+// This method returns the "proof of work" (PoW) value that was found while mining the block.
+func (b Block) Nonce() int {
+	return b.pow
+}
+
+// This method returns a copy of the block's arbitrary data. Transactions are available
+// through Transactions().
+// A deep copy is returned so that callers cannot mutate the block and quietly break IsValid().
+func (b Block) Data() map[string]interface{} {
+	return copyData(b.data)
+}
+
+// This function deep-copies block data, including any nested maps and slices.
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyData(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// This method calculates the cryptographic hash of a block based on its height, previous hash, Merkle root, data, timestamp,
+// and the difficulty or target it was mined under.
+// It uses the chain's hash algorithm, SHA-256 by default, to generate a unique hash value for each block.
+// The timestamp is formatted as UTC RFC 3339 with nanoseconds, so that a block read back from
+// disk (which loses the monotonic clock reading and the zone name) reproduces the same hash.
+// The data is serialized canonically with sorted keys (see canonicalJSON), taken from the
+// cache filled by setData when available. Data that can't be marshaled yields an empty hash.
+// Because the proof-of-work requirement is part of the hash, a block can't claim a lower
+// difficulty than it was mined at without its hash failing to recompute. The difficulty, the
+// target and the pow are separated by slashes, so that moving digits between them changes
+// the hash too.
+func (b Block) calculateHash(newHash func() hash.Hash) string {
+	data := b.dataJSON
+	if data == nil {
+		var err error
+		if data, err = canonicalJSON(b.data); err != nil {
+			// Data that can't be marshaled has no hash, so it never passes validation.
+			return ""
+		}
+	}
+	var target string
+	if b.target != nil {
+		target = b.target.Text(16)
+	}
+	blockData := strconv.Itoa(b.height) + b.previousHash + b.merkleRoot + string(data) + b.timestamp.UTC().Format(time.RFC3339Nano) +
+		strconv.Itoa(b.difficulty) + "/" + target + "/" + strconv.Itoa(b.pow)
+	return fmt.Sprintf("%x", digest(newHash, []byte(blockData)))
+}
+
+// This method reports whether the block's stored hash equals its recomputed SHA-256 hash and
+// has at least the given number of leading zero hex characters, i.e. whether its proof of work
+// is valid at that difficulty. For chains created with a custom hash algorithm, use Validate.
+func (b Block) HasValidProofOfWork(difficulty int) bool {
+	return b.hasValidProofOfWork(difficulty, sha256.New)
+}
+
+// This method implements HasValidProofOfWork for the given hash algorithm.
+func (b Block) hasValidProofOfWork(difficulty int, newHash func() hash.Hash) bool {
+	return b.hash == b.calculateHash(newHash) && prefixRule(difficulty)(b.hash)
+}
+
+// This method returns the proof-of-work rule the block was mined under: its hash must be below
+// its target if it has one, and otherwise must have as many leading zeros as its difficulty.
+func (b Block) proofRule() func(hash string) bool {
+	if b.target != nil {
+		return targetRule(b.target)
+	}
+	return prefixRule(b.difficulty)
+}
+
+// This function returns the rule that a hash has at least difficulty leading zero hex characters.
+// A difficulty above the length of the hash is never satisfied.
+func prefixRule(difficulty int) func(hash string) bool {
+	difficulty = max(difficulty, 0)
+	return func(hash string) bool {
+		return len(hash) >= difficulty && strings.TrimLeft(hash[:difficulty], "0") == ""
+	}
+}
+
+// This function returns the rule that a hash, interpreted as a big-endian number, is below the target.
+func targetRule(target *big.Int) func(hash string) bool {
+	return func(hash string) bool {
+		value, ok := new(big.Int).SetString(hash, 16)
+		return ok && value.Cmp(target) < 0
+	}
+}
+
+// This method sets the block's data and caches its canonical serialization (see
+// canonicalJSON) for calculateHash.
+// The data of a block never changes after it has been set, so the cache stays valid; the
+// stored hash is still compared against a full recomputation of the hash during validation.
+// An error is returned if the data can't be marshaled to JSON, for example because it holds a
+// channel or a function.
+func (b *Block) setData(data map[string]interface{}) error {
+	b.data = data
+	var err error
+	b.dataJSON, err = canonicalJSON(data)
+	return err
 }
 
 // This method mines a new block by adjusting the "proof of work" (PoW) value until the hash meets the required difficulty.
 // The difficulty is determined by the number of leading zeros in the hash. A higher difficulty requires more computational power to mine a block.
-func (b *Block) mine(difficulty int) {
-	for !strings.HasPrefix(b.hash, strings.Repeat("0", difficulty)) {
+// If the block has a target threshold, the hash must instead be below the target (see proofRule).
+// Mining is aborted with an error when the context is cancelled or its deadline passes, or when
+// the PoW value would overflow.
+func (b *Block) mine(ctx context.Context, newHash func() hash.Hash) error {
+	satisfied := b.proofRule()
+	b.hash = b.calculateHash(newHash)
+	for !satisfied(b.hash) {
+		if b.pow%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("%w: %w", ErrMiningCancelled, err)
+			}
+		}
+		if b.pow == math.MaxInt {
+			return errors.New("mining aborted: proof of work overflowed")
+		}
 		b.pow++
-		b.hash = b.calculateHash()
+		b.hash = b.calculateHash(newHash)
 	}
+	return nil
 }
 
 // This function creates a new blockchain with a genesis block and an empty chain.
 // The difficulty is set to a default value of 2, which means that the hash must start with two leading zeros to be considered valid.
 // The difficulty can be adjusted based on the expected time required to mine a new block and the computational power available.
 // A higher difficulty will make it more difficult to mine a new block but will also require more computational power.
+// A difficulty of 0 means no proof of work is required. Negative difficulties are clamped to 0,
+// and difficulties above MaxDifficulty, which could never be satisfied, are clamped to it.
+// Every step of difficulty multiplies the expected work by 16, so difficulties far below the
+// maximum are already impractical: difficulty 8 takes about 4 billion hash attempts per block.
+// Use NewBlockchain to have an out-of-range difficulty reported as an error instead.
+// The genesis block is stamped with the current time, so every chain created this way has a
+// genesis block of its own. Nodes that must agree on one network, for example to exchange
+// chains with ReplaceChain or SyncWith, create it with CreateBlockchainWithGenesis instead.
 func CreateBlockchain(difficulty int) Blockchain {
-	// Set the hash of our genesis block to "0". Because it is the first block in the blockchain,
-	// there is no value for the previous hash, and the data property is empty.
-	genesisBlock := Block{
-		hash:      "0",
-		timestamp: time.Now(),
+	return CreateBlockchainWithGenesis(difficulty, nil, time.Now())
+}
+
+// The highest possible difficulty of a chain hashed with SHA-256: the number of hex characters
+// in its hashes. Chains with another hash algorithm (see CreateBlockchainWithHasher) have the
+// number of hex characters of that algorithm's hashes as their maximum instead. Difficulties
+// anywhere near the maximum could only be mined in theory; see CreateBlockchain.
+const MaxDifficulty = 2 * sha256.Size
+
+// This function is like CreateBlockchain, but returns an error if the difficulty is negative
+// or above MaxDifficulty instead of clamping it.
+func NewBlockchain(difficulty int) (Blockchain, error) {
+	return NewBlockchainWithGenesis(difficulty, nil, time.Now())
+}
+
+// This function limits the difficulty to the range [0, limit].
+func clampDifficulty(difficulty, limit int) int {
+	return min(max(difficulty, 0), limit)
+}
+
+// This function returns the highest difficulty of blocks hashed with the given algorithm: the
+// number of hex characters in its hashes.
+func maxDifficulty(newHash func() hash.Hash) int {
+	return 2 * newHash().Size()
+}
+
+// This function returns a fixed genesis timestamp, the Unix epoch, for networks that have no
+// particular launch time to agree on. See CreateBlockchainWithGenesis. It is a function rather
+// than a variable so that no code can change the instant all nodes agree on.
+func GenesisEpoch() time.Time {
+	return time.Unix(0, 0).UTC()
+}
+
+// This function creates a new blockchain whose genesis block carries the given data and
+// timestamp. Two blockchains created with the same genesis data and time share an identical
+// genesis block, which makes them comparable and tests deterministic: all nodes of a network
+// pass the same parameters, for example GenesisEpoch() as the time, so that they start from the
+// same genesis block and accept each other's chains. Opening balances can be included in
+// the genesis data under AllocationsKey; amounts may be of any numeric type.
+// Genesis data that can't be marshaled to JSON leaves the genesis block without a hash, and
+// allocations that CreateBlockchainWithAllocations would reject, such as negative amounts,
+// leave the genesis block invalid; Validate reports both. Use NewBlockchainWithGenesis to have
+// them reported as an error instead.
+// The difficulty is clamped like in CreateBlockchain.
+func CreateBlockchainWithGenesis(difficulty int, genesisData map[string]interface{}, genesisTime time.Time) Blockchain {
+	return createBlockchain(difficulty, genesisData, genesisTime, sha256.New)
+}
+
+// This function is like CreateBlockchainWithGenesis, but returns an error if the difficulty is
+// out of range like NewBlockchain, if the genesis data can't be marshaled to JSON, for
+// example because it holds a channel or a function, or if its allocations are invalid.
+func NewBlockchainWithGenesis(difficulty int, genesisData map[string]interface{}, genesisTime time.Time) (Blockchain, error) {
+	if difficulty < 0 || difficulty > MaxDifficulty {
+		return Blockchain{}, fmt.Errorf("difficulty %d out of range [0, %d]", difficulty, MaxDifficulty)
 	}
-	return Blockchain{
-		genesisBlock,
-		[]Block{genesisBlock},
-		difficulty,
+	if _, err := canonicalJSON(genesisData); err != nil {
+		return Blockchain{}, fmt.Errorf("invalid genesis data: %w", err)
 	}
+	b := CreateBlockchainWithGenesis(difficulty, genesisData, genesisTime)
+	if err := checkAllocations(b.genesisBlock.data); err != nil {
+		return Blockchain{}, fmt.Errorf("invalid genesis data: %w", err)
+	}
+	return b, nil
+}
+
+// This function creates a new blockchain for generating reproducible test vectors, for example
+// to check another implementation against this one. The genesis block is stamped with
+// GenesisEpoch(), and the chain's clock is frozen at that instant, so every block and every
+// transaction stamped by the chain carries the same timestamp and the timestamp's
+// contribution to the hashes is constant: the same data and difficulty always produce the same hashes, across
+// runs and machines.
+// This is for tests only and insecure: timestamps no longer record when blocks were mined, so
+// neither their order nor the retargeting of the difficulty can rely on them. Use
+// CreateBlockchain or CreateBlockchainWithGenesis for real chains. Calling SetClock undoes
+// the option for blocks mined afterwards.
+func CreateTestVectorBlockchain(difficulty int, genesisData map[string]interface{}) Blockchain {
+	epoch := GenesisEpoch()
+	b := createBlockchain(difficulty, genesisData, epoch, sha256.New)
+	b.now = func() time.Time { return epoch }
+	return b
 }
 
-// This method adds a new block to the blockchain with the provided transaction data and
+// This function creates a new in-memory blockchain from all construction parameters.
+func createBlockchain(difficulty int, genesisData map[string]interface{}, genesisTime time.Time, newHash func() hash.Hash) Blockchain {
+	store := NewMemoryStore()
+	store.Append(newGenesisBlock(genesisData, genesisTime, newHash))
+	return newBlockchain(difficulty, store, newHash)
+}
+
+// This function creates a genesis block holding a copy of the data. The copy is decoded from
+// the data's canonical JSON, so that the genesis block holds the same values, for example
+// float64 rather than int amounts, as it does after it has been saved and loaded again.
+func newGenesisBlock(data map[string]interface{}, timestamp time.Time, newHash func() hash.Hash) Block {
+	// Because the genesis block is the first block in the blockchain, there is no value for
+	// the previous hash. Its hash is computed from its data and timestamp like any other
+	// block, but it is not mined.
+	genesisBlock := Block{timestamp: timestamp}
+	copied := copyData(data)
+	if content, err := canonicalJSON(data); err == nil && data != nil {
+		copied = nil
+		json.Unmarshal(content, &copied)
+	}
+	genesisBlock.setData(copied)
+	genesisBlock.hash = genesisBlock.calculateHash(newHash)
+	return genesisBlock
+}
+
+// This function creates a blockchain kept in the given block store, such as a FileStore.
+// If the store is empty, a new genesis block is appended to it. Otherwise the store's first
+// block is used as the genesis block, and the stored chain is validated.
+// The difficulty is clamped like in CreateBlockchain. The stored chain is validated as paying
+// no mining reward; use CreateBlockchainWithStoreAndReward to reopen a rewarded chain.
+func CreateBlockchainWithStore(difficulty int, store BlockStore) (Blockchain, error) {
+	return CreateBlockchainWithStoreAndReward(difficulty, store, 0)
+}
+
+// This function works like CreateBlockchainWithStore, except that the mining reward is set
+// (see SetMiningReward) before the stored chain is validated, since block stores record only
+// the blocks, not the reward they were mined at.
+func CreateBlockchainWithStoreAndReward(difficulty int, store BlockStore, miningReward float64) (Blockchain, error) {
+	if store.Len() == 0 {
+		if err := store.Append(newGenesisBlock(nil, time.Now(), sha256.New)); err != nil {
+			return Blockchain{}, err
+		}
+	}
+	b := newBlockchain(difficulty, store, nil)
+	b.miningReward = miningReward
+	if err := b.validate(); err != nil {
+		return Blockchain{}, fmt.Errorf("stored blockchain is not valid: %w", err)
+	}
+	return b, nil
+}
+
+// This function wraps a store holding at least the genesis block, hashed with the given
+// algorithm, into a blockchain. A nil algorithm means SHA-256. The difficulty is clamped to
+// the range of the algorithm.
+func newBlockchain(difficulty int, store BlockStore, newHash func() hash.Hash) Blockchain {
+	genesisBlock, _ := store.Get(0)
+	b := Blockchain{&chainState{
+		genesisBlock: genesisBlock,
+		store:        store,
+		hasher:       newHash,
+		maxClockSkew: DefaultMaxClockSkew,
+	}}
+	b.difficulty = clampDifficulty(difficulty, b.maxDifficulty())
+	return b
+}
+
+// This method records a transaction of the given amount from one account to another.
+// It is a convenience wrapper around SubmitTransaction that stamps the transaction with the
+// blockchain's clock.
+// An error is returned, and nothing is appended, if "from" or "to" is empty, if the amount
+// is negative, NaN or infinite, if the same transaction has already been recorded, or if
+// mining did not produce a valid hash.
+func (b *Blockchain) AddTransaction(from, to string, amount float64) error {
+	return b.AddTransactionContext(context.Background(), from, to, amount)
+}
+
+// This method is like AddTransaction, but aborts mining when the context is done.
+func (b *Blockchain) AddTransactionContext(ctx context.Context, from, to string, amount float64) error {
+	return b.SubmitTransactionContext(ctx, Transaction{From: from, To: to, Amount: amount})
+}
+
+// This method adds a new block to the blockchain with the provided transaction and
 // mining it with the specified difficulty.
 // The new block's "previousHash" is set to the hash of the last block in the chain, ensuring
 // that the blockchain is a linked list of blocks.
-// The new block's "hash" is calculated based on the previous hash, the transaction data, and
-// the timestamp. The mining process adjusts the "proof of work" (PoW) value until the hash
-// meets the required difficulty.
-// The transaction data is stored as a map of key-value pairs "blockData", where the keys and
-// values are strings and floats, respectively.
+// The new block's "hash" is calculated based on the previous hash, the Merkle root of the
+// block's transactions, and the timestamp. The mining process adjusts the "proof of work"
+// (PoW) value until the hash meets the required difficulty.
 // The amount of work required to mine a new block is stored in the "proof of work" (PoW)
 // value of the new block.
-func (b *Blockchain) AddTransaction(from, to string, amount float64) {
-	blockData := map[string]interface{}{
-		"from":   from,
-		"to":     to,
-		"amount": amount,
+// A transaction without a timestamp is stamped with the blockchain's clock. An error is returned,
+// and nothing is appended, if the transaction is invalid or rejected by strict mode (see
+// SetStrictMode), if a transaction with the same ID (see Transaction.ID) is already on the
+// chain or in the mempool, or if mining did not produce a valid hash.
+func (b *Blockchain) SubmitTransaction(tx Transaction) error {
+	return b.SubmitTransactionContext(context.Background(), tx)
+}
+
+// This method is like SubmitTransaction, but aborts mining when the context is cancelled or
+// its deadline passes. The block is not appended in that case, and the context's error is
+// wrapped in the returned error.
+func (b *Blockchain) SubmitTransactionContext(ctx context.Context, tx Transaction) error {
+	_, err := b.submitTransaction(ctx, tx, false)
+	return err
+}
+
+// This method is like AddTransaction, but also returns the freshly mined block holding the
+// transaction, for example to answer with a receipt carrying the block's hash.
+func (b *Blockchain) AddTransactionBlock(from, to string, amount float64) (Block, error) {
+	return b.submitTransaction(context.Background(), Transaction{From: from, To: to, Amount: amount}, false)
+}
+
+// This method mines and appends a block holding an arbitrary payload, such as a document or a
+// vote, instead of a transfer. The block pays the mining reward like any other mined block.
+// The payload is stored in its JSON form, so that the block reads back identically after
+// SaveToFile and LoadFromFile: structs become maps and numbers become float64. An error is
+// returned, and nothing is appended, if the payload is empty or can't be marshaled to JSON,
+// for example because it holds a channel or a function.
+func (b *Blockchain) AddData(payload map[string]interface{}) (Block, error) {
+	if len(payload) == 0 {
+		return Block{}, errors.New("invalid payload: payload is empty")
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return Block{}, fmt.Errorf("invalid payload: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return Block{}, fmt.Errorf("invalid payload: %w", err)
+	}
+	block, err := b.mineBlock(context.Background(), func() ([]Transaction, map[string]interface{}, error) {
+		return nil, data, nil
+	}, nil)
+	if err != nil {
+		return Block{}, err
+	}
+	b.notifyBlockMined(block)
+	return block, nil
+}
+
+// This method mines and appends a block holding no transactions and no data, for example as a
+// heartbeat that keeps the chain advancing while no transactions arrive. Like any mined block,
+// it carries the coinbase transaction if a mining reward is configured.
+func (b *Blockchain) MineEmptyBlock() (Block, error) {
+	block, err := b.mineBlock(context.Background(), func() ([]Transaction, map[string]interface{}, error) {
+		return nil, nil, nil
+	}, nil)
+	if err != nil {
+		return Block{}, err
+	}
+	b.notifyBlockMined(block)
+	return block, nil
+}
+
+// This method validates the transaction, then mines and appends a block holding it. If
+// requireFunds is set, the transaction is also rejected if its sender can't afford it.
+func (b *Blockchain) submitTransaction(ctx context.Context, tx Transaction, requireFunds bool) (Block, error) {
+	if err := tx.validate(); err != nil {
+		b.notifyTransactionRejected(tx, err)
+		return Block{}, err
+	}
+	var rejected error
+	block, err := b.mineBlock(ctx, func() ([]Transaction, map[string]interface{}, error) {
+		if tx.Timestamp.IsZero() {
+			tx.Timestamp = b.clock()
+		}
+		if rejected = b.checkAdmissible(tx, requireFunds); rejected != nil {
+			return nil, nil, rejected
+		}
+		return []Transaction{tx}, nil, nil
+	}, nil)
+	if rejected != nil {
+		b.notifyTransactionRejected(tx, rejected)
+	}
+	if err != nil {
+		return Block{}, err
+	}
+	b.notifyBlockMined(block)
+	return block, nil
+}
+
+// This method mines and appends a block holding the data and the transactions chosen by
+// prepare, followed by the coinbase transaction paying the mining reward and the
+// transactions' fees to the miner. prepare is called with the exclusive lock held and returns
+// the block's transactions and data, or an error that aborts mining; appended, if not nil, is
+// called with the lock still held once the block is on the chain. The nonce search runs
+// without the lock, so that readers, such as Snapshot and Metrics, aren't blocked while
+// mining. If the tip or a setting the block was built from changed in the meantime, the
+// mined block is discarded and prepare is called again for a fresh one.
+// The caller must not hold the lock.
+func (b *Blockchain) mineBlock(ctx context.Context, prepare func() ([]Transaction, map[string]interface{}, error), appended func()) (Block, error) {
+	for {
+		b.lock()
+		txs, data, err := prepare()
+		if err != nil {
+			b.mu.Unlock()
+			return Block{}, err
+		}
+		newBlock, basis, err := b.newCandidate(txs, data)
+		if err != nil {
+			b.mu.Unlock()
+			return Block{}, err
+		}
+		miner, newHash := b.miner(), b.hashFunc()
+		b.log("mine_start", map[string]interface{}{
+			"height":       newBlock.height,
+			"difficulty":   newBlock.difficulty,
+			"transactions": len(newBlock.transactions),
+		})
+		b.mu.Unlock()
+
+		start := time.Now()
+		newBlock.mining = &miningSession{ctx: ctx, newHash: newHash}
+		err = miner.Mine(&newBlock, newBlock.difficulty)
+		newBlock.mining = nil
+		elapsed := time.Since(start)
+
+		b.lock()
+		if err != nil {
+			b.log("mine_failed", map[string]interface{}{"height": newBlock.height, "error": err.Error()})
+			b.mu.Unlock()
+			return Block{}, err
+		}
+		b.log("mine_complete", map[string]interface{}{
+			"height":  newBlock.height,
+			"nonce":   newBlock.pow,
+			"elapsed": elapsed,
+			"hash":    newBlock.hash,
+		})
+		if newBlock.hash != newBlock.calculateHash(newHash) || !newBlock.proofRule()(newBlock.hash) {
+			b.mu.Unlock()
+			return Block{}, errors.New("mining produced an invalid hash")
+		}
+		if newBlock.difficulty != basis.difficulty || newBlock.target != basis.target {
+			b.mu.Unlock()
+			return Block{}, errors.New("mining changed the proof-of-work rule of the block")
+		}
+		if b.currentBasis() != basis {
+			b.log("mine_stale", map[string]interface{}{"height": newBlock.height, "hash": newBlock.hash})
+			b.mu.Unlock()
+			continue
+		}
+		err = b.appendMined(newBlock, elapsed, appended)
+		b.mu.Unlock()
+		if err != nil {
+			return Block{}, err
+		}
+		return newBlock, nil
+	}
+}
+
+// The state of the chain a mined block is built from. A block mined while any of it changed
+// no longer fits the chain, and is mined again.
+type miningBasis struct {
+	tip          string   // the hash of the block the new block extends
+	difficulty   int      // the difficulty the new block is mined at
+	target       *big.Int // the target threshold the new block is mined below; it is replaced, never modified
+	miningReward float64  // the reward the coinbase transaction pays
+	minerAddress string   // the account the coinbase transaction pays
+}
+
+// This method returns the current basis for mining a block. The caller must hold the lock.
+func (b *Blockchain) currentBasis() miningBasis {
+	tip, _ := b.tip()
+	return miningBasis{
+		tip:          tip.hash,
+		difficulty:   b.difficulty,
+		target:       b.target,
+		miningReward: b.miningReward,
+		minerAddress: b.minerAddress,
+	}
+}
+
+// This method builds an unmined block holding the data and the transactions, followed by the
+// coinbase transaction, on top of the tip, and returns it with the basis it was built from.
+// The caller must hold the lock.
+func (b *Blockchain) newCandidate(txs []Transaction, data map[string]interface{}) (Block, miningBasis, error) {
+	transactions := make([]Transaction, len(txs), len(txs)+1)
+	copy(transactions, txs)
+	payout := moneyOf(b.miningReward)
+	for _, tx := range txs {
+		payout += moneyOf(tx.Fee)
+	}
+	if payout > 0 && b.minerAddress != "" {
+		transactions = append(transactions, Transaction{
+			From:      CoinbaseAddress,
+			To:        b.minerAddress,
+			Amount:    payout.Float64(),
+			Timestamp: b.clock(),
+		})
+	}
+	lastBlock, err := b.tip()
+	if err != nil {
+		return Block{}, miningBasis{}, err
 	}
-	lastBlock := b.chain[len(b.chain)-1]
 	newBlock := Block{
-		data:         blockData,
+		transactions: transactions,
 		previousHash: lastBlock.hash,
-		timestamp:    time.Now(),
+		timestamp:    b.clock(),
+		pow:          b.startNonce,
+		merkleRoot:   merkleRoot(transactions),
+		height:       lastBlock.height + 1,
+		difficulty:   b.difficulty,
+		target:       b.target,
+	}
+	if err := newBlock.setData(data); err != nil {
+		return Block{}, miningBasis{}, fmt.Errorf("invalid block data: %w", err)
+	}
+	return newBlock, b.currentBasis(), nil
+}
+
+// This method appends a freshly mined block that extends the tip, updates the indexes and
+// metrics, and retargets the difficulty. The caller must hold the exclusive lock.
+func (b *Blockchain) appendMined(newBlock Block, elapsed time.Duration, appended func()) error {
+	lastBlock, err := b.tip()
+	if err != nil {
+		return err
+	}
+	if err := b.checkMinimumWork(newBlock); err != nil {
+		return err
+	}
+	if err := b.store.Append(newBlock); err != nil {
+		return err
+	}
+	b.indexBlock(newBlock)
+	b.recordMined(elapsed)
+	b.retarget(lastBlock)
+	if appended != nil {
+		appended()
+	}
+	return nil
+}
+
+// Recalculate the hash of the genesis block and check that the chain still starts with it.
+// Then recalculate the Merkle root and the hash of every other block on the blockchain,
+// compare them with the stored values, check that the hash satisfies the difficulty the block
+// was mined at (or its target threshold), which must not be below the minimum difficulty
+// (see SetMinDifficulty), and check whether the
+// "previousHash" value of every block is equal to the hash value of the block before it, and
+// whether its height is one more than the height of the block before it.
+// Every block's timestamp must also not be earlier than its predecessor's (equal timestamps,
+// of blocks mined within the same instant, are allowed), nor more than the maximum clock skew
+// ahead of the local clock (see SetMaxClockSkew), and every signed transaction's signature
+// must verify against its "from" party; transactions from key-derived addresses (see
+// AddressOf) must be signed. Coinbase transactions are exempt from signature checks.
+// No transaction may be recorded twice, in one block or in two, every user transaction must be well-formed
+// as on submission, and a block may end with one coinbase transaction paying no more than the
+// mining reward (see SetMiningReward) plus the fees of the block's user transactions.
+// If any check fail, the blockchain has been tampered with. The returned error wraps
+// ErrChainTampered, and names the first failing block and which check failed.
+func (b *Blockchain) Validate() error {
+	return b.ValidateContext(context.Background())
+}
+
+// This method is like Validate, but aborts when the context is cancelled or its deadline
+// passes, so that a startup routine can bound how long it checks a very large chain. The
+// context is checked every validationBatchSize blocks, and its error is wrapped in the
+// returned error.
+func (b *Blockchain) ValidateContext(ctx context.Context) error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	return b.validateContext(ctx)
+}
+
+// This method implements Validate without taking the lock.
+func (b *Blockchain) validate() error {
+	return b.validateContext(context.Background())
+}
+
+// This method implements ValidateContext without taking the lock. Failures are reported to
+// the logger as "validate_failed" events.
+func (b *Blockchain) validateContext(ctx context.Context) error {
+	index, err := b.firstInvalid(ctx)
+	if err != nil && index >= 0 {
+		b.log("validate_failed", map[string]interface{}{"index": index, "error": err.Error()})
+		return fmt.Errorf("%w: %w", ErrChainTampered, err)
 	}
-	newBlock.mine(b.difficulty)
-	b.chain = append(b.chain, newBlock)
+	return err
 }
 
-// Recalculate the hash of every block on the blockchain, compare them with the stored hash
-// values of the other blocks, and check whether the "previousHash" value of every block
-// is equal to the hash value of the block before it.
-// If any check fail, the blockchain has been tampered with.
-func (b Blockchain) IsValid() bool {
-	for i := range b.chain[1:] {
-		previousBlock := b.chain[i]
-		currentBlock := b.chain[i+1]
-		if currentBlock.hash != currentBlock.calculateHash() || currentBlock.previousHash != previousBlock.hash {
-			return false
+// This method performs the checks of Validate, and returns the index of the first failing
+// block along with the error. The index is -1 if validation was aborted by the context.
+func (b *Blockchain) firstInvalid(ctx context.Context) (int, error) {
+	genesis, err := b.store.Get(0)
+	if err != nil {
+		return 0, fmt.Errorf("block 0: %w", err)
+	}
+	if genesis.hash == "" || genesis.hash != genesis.calculateHash(b.hashFunc()) {
+		return 0, errors.New("block 0: genesis hash mismatch")
+	}
+	if genesis.height != 0 {
+		return 0, errors.New("block 0: genesis height is not 0")
+	}
+	if err := checkAllocations(genesis.data); err != nil {
+		return 0, fmt.Errorf("block 0: %w", err)
+	}
+	if genesis.hash != b.genesisBlock.hash || b.genesisBlock.hash != b.genesisBlock.calculateHash(b.hashFunc()) {
+		return 0, errors.New("block 0: does not match the genesis block")
+	}
+	latest := b.clock().Add(b.maxClockSkew)
+	if genesis.timestamp.After(latest) {
+		return 0, errors.New("block 0: timestamp is in the future")
+	}
+	// Recomputing each block's hashes is independent of the other blocks, so it is spread over
+	// all CPUs one batch at a time; only the cheap linkage checks run serially.
+	previousBlock := genesis
+	seen := make(map[string]int)
+	for start := 1; start < b.store.Len(); start += validationBatchSize {
+		if err := ctx.Err(); err != nil {
+			return -1, fmt.Errorf("validation aborted at block %d: %w", start, err)
+		}
+		end := min(start+validationBatchSize, b.store.Len())
+		batch := make([]Block, 0, end-start)
+		var readErr error
+		for i := start; i < end; i++ {
+			block, err := b.store.Get(i)
+			if err != nil {
+				readErr = fmt.Errorf("block %d: %w", i, err)
+				break
+			}
+			batch = append(batch, block)
+		}
+		errs := b.checkBlocksParallel(start, batch)
+		for j, currentBlock := range batch {
+			if errs[j] != nil {
+				return start + j, errs[j]
+			}
+			if err := checkLink(start+j, previousBlock, currentBlock, latest); err != nil {
+				return start + j, err
+			}
+			if err := recordIDs(seen, start+j, currentBlock); err != nil {
+				return start + j, fmt.Errorf("block %d: %w", start+j, err)
+			}
+			previousBlock = currentBlock
+		}
+		if readErr != nil {
+			return start + len(batch), readErr
+		}
+	}
+	return -1, nil
+}
+
+// This method checks a single non-genesis block at the given index against its predecessor.
+// Timestamps later than latest are rejected as future-dated.
+func (b *Blockchain) validateBlock(index int, previousBlock, currentBlock Block, latest time.Time) error {
+	if err := b.checkBlock(index, currentBlock); err != nil {
+		return err
+	}
+	return checkLink(index, previousBlock, currentBlock, latest)
+}
+
+// One of the checks of a block that don't depend on other blocks, paired with the problem
+// Diagnose reports when it fails.
+type blockCheck struct {
+	problem Problem
+	check   func(b *Blockchain, block Block) error
+}
+
+// The checks run by checkBlock, in order. Diagnose runs the same checks, so that it reports
+// every block that Validate rejects.
+var blockChecks = []blockCheck{
+	{ProblemDuplicateTx, (*Blockchain).checkUnique},
+	{ProblemMerkleMismatch, (*Blockchain).checkMerkleRoot},
+	{ProblemHashMismatch, (*Blockchain).checkHash},
+	{ProblemBadProofOfWork, (*Blockchain).checkProofOfWork},
+	{ProblemLowWork, (*Blockchain).checkMinimumWork},
+	{ProblemInvalidTx, (*Blockchain).checkTransactions},
+	{ProblemBadSignature, (*Blockchain).checkSignatures},
+}
+
+// This method checks the parts of a block that don't depend on other blocks: the uniqueness
+// of its transactions, its Merkle root, its hash, its proof of work and whether that meets
+// the minimum difficulty, its transactions, and its signatures. See blockChecks.
+func (b *Blockchain) checkBlock(index int, currentBlock Block) error {
+	for _, c := range blockChecks {
+		if err := c.check(b, currentBlock); err != nil {
+			return fmt.Errorf("block %d: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// This method checks that no transaction appears twice in the block.
+func (b *Blockchain) checkUnique(block Block) error {
+	if id, ok := duplicateTransaction(block.transactions); ok {
+		return fmt.Errorf("%w %s", ErrDuplicateTransaction, shortHash(id))
+	}
+	return nil
+}
+
+// This method checks that the block's Merkle root matches its transactions.
+func (b *Blockchain) checkMerkleRoot(block Block) error {
+	if block.merkleRoot != merkleRoot(block.transactions) {
+		return errors.New("merkle root mismatch")
+	}
+	return nil
+}
+
+// This method checks that the block's hash matches its contents.
+func (b *Blockchain) checkHash(block Block) error {
+	if block.hash != block.calculateHash(b.hashFunc()) {
+		return errors.New("hash mismatch")
+	}
+	return nil
+}
+
+// This method checks that the block's hash satisfies the rule the block was mined under.
+func (b *Blockchain) checkProofOfWork(block Block) error {
+	if !block.proofRule()(block.hash) {
+		return errors.New("proof of work does not satisfy the rule it was mined under")
+	}
+	return nil
+}
+
+// This method checks that the block was mined under a rule at least as hard as the minimum
+// difficulty (see SetMinDifficulty), whether under a difficulty or a target threshold.
+func (b *Blockchain) checkMinimumWork(block Block) error {
+	if b.minDifficulty > 0 && block.work().Cmp(difficultyWork(b.minDifficulty)) < 0 {
+		return fmt.Errorf("proof of work is below the minimum difficulty %d", b.minDifficulty)
+	}
+	return nil
+}
+
+// This method checks that the block records only what a mined block may: user transactions
+// that are well-formed, as checked on submission, and at most one coinbase transaction, as
+// the last transaction, paying the miner no more than the mining reward plus the fees of the
+// block's user transactions.
+func (b *Blockchain) checkTransactions(block Block) error {
+	var fees Money
+	for i, tx := range block.transactions {
+		if tx.From != CoinbaseAddress {
+			if err := tx.validateFields(); err != nil {
+				return err
+			}
+			fees += moneyOf(tx.Fee)
+			continue
+		}
+		if i != len(block.transactions)-1 {
+			return fmt.Errorf("%w: coinbase transaction %d is not the last transaction", ErrInvalidTransaction, i)
+		}
+		payout, err := ToMoney(tx.Amount)
+		if err != nil {
+			return fmt.Errorf("%w: coinbase: %w", ErrInvalidTransaction, err)
+		}
+		if tx.To == "" || payout < 0 {
+			return fmt.Errorf("%w: coinbase pays %v to %q", ErrInvalidTransaction, tx.Amount, tx.To)
+		}
+		if limit := moneyOf(b.miningReward) + fees; payout > limit {
+			return fmt.Errorf("%w: coinbase pays %s, more than the reward plus fees of %s", ErrInvalidTransaction, payout, limit)
+		}
+	}
+	return nil
+}
+
+// This method checks the signatures of the block's user transactions that need one.
+func (b *Blockchain) checkSignatures(block Block) error {
+	for _, tx := range block.transactions {
+		if tx.From != CoinbaseAddress && tx.needsSignature() {
+			if err := tx.VerifySignature(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// This function checks how a block links to its predecessor: the previous hash, the height,
+// and the ordering and skew of the timestamps.
+func checkLink(index int, previousBlock, currentBlock Block, latest time.Time) error {
+	if currentBlock.previousHash != previousBlock.hash {
+		return fmt.Errorf("block %d: previous hash does not match block %d", index, index-1)
+	}
+	if currentBlock.height != previousBlock.height+1 {
+		return fmt.Errorf("block %d: height %d does not follow block %d", index, currentBlock.height, index-1)
+	}
+	if currentBlock.timestamp.After(latest) {
+		return fmt.Errorf("block %d: timestamp is in the future", index)
+	}
+	if currentBlock.timestamp.Before(previousBlock.timestamp) {
+		return fmt.Errorf("block %d: timestamp %s is earlier than block %d (%s)", index,
+			currentBlock.timestamp.UTC().Format(time.RFC3339Nano), index-1, previousBlock.timestamp.UTC().Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// This method reports whether the blockchain is valid. See Validate for the checks performed.
+func (b *Blockchain) IsValid() bool {
+	return b.Validate() == nil
+}
+
+// This method checks only the latest block against its predecessor, using the same checks as
+// Validate, in constant time. It trusts that every earlier block was validated when it was
+// appended, as the blockchain's own methods do, so it is meant for cheaply re-checking a
+// long-running node's tip. It is not a substitute for a full Validate after loading a chain
+// from disk or receiving one from a peer. If the chain holds only the genesis block, the
+// genesis checks of Validate are performed instead.
+func (b *Blockchain) ValidateTip() error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	n := b.store.Len()
+	if n <= 1 {
+		return b.validate()
+	}
+	previousBlock, err := b.store.Get(n - 2)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", n-2, err)
+	}
+	tip, err := b.store.Get(n - 1)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", n-1, err)
+	}
+	if err := b.validateBlock(n-1, previousBlock, tip, b.clock().Add(b.maxClockSkew)); err != nil {
+		b.log("validate_failed", map[string]interface{}{"index": n - 1, "error": err.Error()})
+		return fmt.Errorf("%w: %w", ErrChainTampered, err)
+	}
+	return nil
+}
+
+// This method returns a copy of all blocks on the blockchain, starting with the genesis block.
+// The returned slice does not share its backing array with the chain, so callers can't
+// append to or reorder the real chain. If the block store fails, only the blocks read before
+// the failure are returned.
+func (b *Blockchain) Blocks() []Block {
+	b.rlock()
+	defer b.mu.RUnlock()
+	blocks, _ := b.collect()
+	return blocks
+}
+
+// This method returns the number of blocks on the blockchain, including the genesis block.
+func (b *Blockchain) Len() int {
+	b.rlock()
+	defer b.mu.RUnlock()
+	return b.store.Len()
+}
+
+// This method returns the block at the given position, where 0 is the genesis block.
+// An error is returned if the index is out of range.
+func (b *Blockchain) BlockAt(index int) (Block, error) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	if index < 0 || index >= b.store.Len() {
+		return Block{}, fmt.Errorf("%w: index %d out of range [0, %d]", ErrBlockNotFound, index, b.store.Len()-1)
+	}
+	return b.store.Get(index)
+}
+
+// This method returns the tip of the chain: the most recently added block, or the genesis
+// block if nothing has been added yet. The zero Block is returned if the block store fails.
+func (b *Blockchain) LatestBlock() Block {
+	b.rlock()
+	defer b.mu.RUnlock()
+	block, _ := b.tip()
+	return block
+}
+
+// This method computes the net balance of an account by walking all blocks after the genesis
+// block. The amount and the fee of every transaction are subtracted when the account is the
+// "from" party, and the amount is added when it is the "to" party. Mining rewards credited to the account are included, and
+// so is the account's opening balance if the chain was created with CreateBlockchainWithAllocations.
+// The amounts are summed exactly as Money.
+func (b *Blockchain) BalanceOf(account string) float64 {
+	b.rlock()
+	defer b.mu.RUnlock()
+	return b.balance(account).Float64()
+}
+
+// This method implements BalanceOf without taking the lock. The balance index is used if it
+// is enabled (see EnableUTXOIndex).
+func (b *Blockchain) balance(account string) Money {
+	if b.utxo != nil {
+		return b.utxo.balances[account]
+	}
+	balances := map[string]Money{account: b.allocations()[account]}
+	for _, block := range b.blocks(1) {
+		for _, tx := range block.transactions {
+			if tx.From == account || tx.To == account {
+				applyTx(balances, tx)
+			}
+		}
+	}
+	return balances[account]
+}
+
+// This function applies the transaction to the balances: the recipient is credited the
+// amount, and the sender is debited the amount plus the fee, unless the transaction is a
+// coinbase transaction, which creates the amount. Every balance computation of the chain goes
+// through it, so that they all agree.
+func applyTx(balances map[string]Money, tx Transaction) {
+	if tx.From != CoinbaseAddress {
+		balances[tx.From] -= tx.debit()
+	}
+	balances[tx.To] += moneyOf(tx.Amount)
+}
+
+// This method returns the balance of an account minus what it already sends in transactions
+// waiting in the mempool. The caller must hold the lock.
+func (b *Blockchain) spendable(account string) Money {
+	balance := b.balance(account)
+	for _, tx := range b.pending {
+		if tx.From == account {
+			balance -= tx.debit()
+		}
+	}
+	return balance
+}
+
+// This method reports whether the account can afford to send the amount, as a pre-flight
+// check before submitting a payment. See CanAffordWithFee.
+func (b *Blockchain) CanAfford(from string, amount float64) bool {
+	return b.CanAffordWithFee(from, amount, 0)
+}
+
+// This method reports whether the account can afford to send the amount and pay the fee: its
+// balance, minus what it already sends in transactions waiting in the mempool, must cover
+// both, which is the check Wallet.Send performs. Negative or invalid amounts and fees can't be
+// afforded. The answer is advisory only: other transactions may spend the balance between
+// the check and the mining of the payment, so the submission itself can still fail.
+func (b *Blockchain) CanAffordWithFee(from string, amount, fee float64) bool {
+	tx := Transaction{From: from, Amount: amount, Fee: fee}
+	if cost, err := ToMoney(amount); err != nil || cost < 0 || tx.validateFee() != nil {
+		return false
+	}
+	b.rlock()
+	defer b.mu.RUnlock()
+	return tx.debit() <= b.spendable(from)
+}
+
+// This method is like BalanceOf, but checks the running balance after every transaction. An
+// error naming the block is returned if the balance ever goes negative, which can only happen
+// on a chain holding a double spend or an overdraft, or if it overflows Money.
+func (b *Blockchain) BalanceOfChecked(account string) (float64, error) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	balances := map[string]Money{account: b.allocations()[account]}
+	for i, block := range b.blocks(1) {
+		for _, tx := range block.transactions {
+			if tx.From != account && tx.To != account {
+				continue
+			}
+			if tx.To == account && balances[account] > math.MaxInt64-moneyOf(tx.Amount) {
+				return 0, fmt.Errorf("block %d: balance of %q overflows", i, account)
+			}
+			applyTx(balances, tx)
+			if balances[account] < 0 {
+				return 0, fmt.Errorf("block %d: balance of %q goes negative (%s)", i, account, balances[account])
+			}
+		}
+	}
+	return balances[account].Float64(), nil
+}
+
+// This method sets the reward paid to the miner for every mined block. The reward, together
+// with the fees of the block's transactions, is recorded as a coinbase transaction from
+// CoinbaseAddress in the mined block, and is only paid once a miner address has been set with
+// SetMinerAddress; without one, the fees are burned. The genesis block never carries a reward.
+// The reward is also a rule of the chain: blocks whose coinbase transaction pays more than
+// the reward plus fees are rejected, so all nodes of a network must set the same reward, and
+// lowering it invalidates blocks already paid at the higher reward. SaveToFile, SaveCompressed
+// and EncodeGob record the reward. Block stores and SaveStreaming record only the blocks, so
+// reopen their chains with CreateBlockchainWithStoreAndReward and LoadStreamingWithReward.
+func (b *Blockchain) SetMiningReward(amount float64) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.miningReward = amount
+}
+
+// This method sets the account credited with the mining reward of every mined block.
+func (b *Blockchain) SetMinerAddress(address string) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.minerAddress = address
+}
+
+// This method replaces the clock used to timestamp new blocks and transactions, and to check
+// timestamps during validation. Passing nil restores time.Now.
+// With a fixed clock and fixed transactions, mined hashes are fully deterministic across
+// runs, which is useful for tests and benchmarks.
+func (b *Blockchain) SetClock(now func() time.Time) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// This method sets the proof of work value at which mining new blocks starts. The default is 0.
+func (b *Blockchain) SetStartNonce(nonce int) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.startNonce = nonce
+}
+
+// This method returns the current time according to the blockchain's clock.
+func (b *Blockchain) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// This method switches mining to a target-based difficulty: a block is mined when its hash,
+// interpreted as a big-endian number, is below the target. This gives much finer control than
+// the leading-zeros difficulty, which only adjusts in factor-of-16 steps. Each block records
+// the rule it was mined under, so Validate checks blocks mined before and after a change
+// against their own rule. A nil or non-positive target restores the leading-zeros rule.
+// Dynamic difficulty adjustment (SetTargetBlockTime) only affects the leading-zeros rule.
+func (b *Blockchain) SetTargetThreshold(target *big.Int) {
+	b.lock()
+	defer b.mu.Unlock()
+	if target == nil || target.Sign() <= 0 {
+		b.target = nil
+		return
+	}
+	b.target = new(big.Int).Set(target)
+}
+
+// This method returns the difficulty new blocks are mined at. It changes over time if
+// retargeting is enabled with SetTargetBlockTime.
+func (b *Blockchain) Difficulty() int {
+	b.rlock()
+	defer b.mu.RUnlock()
+	return b.difficulty
+}
+
+// This method changes the difficulty new blocks are mined at, for example to raise it as the
+// network grows. Blocks already on the chain keep the difficulty they were mined at and stay
+// valid. An error is returned, and the difficulty is left unchanged, if it is negative or
+// above the maximum of the chain's hash algorithm (see MaxDifficulty), or below the minimum
+// difficulty (see SetMinDifficulty).
+func (b *Blockchain) SetDifficulty(difficulty int) error {
+	b.lock()
+	defer b.mu.Unlock()
+	if limit := b.maxDifficulty(); difficulty < 0 || difficulty > limit {
+		return fmt.Errorf("difficulty %d out of range [0, %d]", difficulty, limit)
+	}
+	if difficulty < b.minDifficulty {
+		return fmt.Errorf("difficulty %d is below the minimum difficulty %d", difficulty, b.minDifficulty)
+	}
+	b.difficulty = difficulty
+	return nil
+}
+
+// This method sets the minimum difficulty: the lowest difficulty any block after the genesis
+// block may be mined at, or the weakest target threshold, measured by the expected number of
+// hash attempts. Validate and AddBlock reject blocks mined with less work, so that a chain
+// can't be forged by re-mining it at a trivial difficulty. The difficulty new blocks are
+// mined at is raised to the minimum if it is lower, and retargeting never lowers it below.
+// The minimum is recorded by SaveToFile, SaveCompressed and EncodeGob. The default is 0,
+// which accepts any difficulty. An error is returned, and nothing is changed, if the
+// difficulty is out of range like in SetDifficulty, or if the chain already holds a block
+// mined with less work.
+func (b *Blockchain) SetMinDifficulty(difficulty int) error {
+	b.lock()
+	defer b.mu.Unlock()
+	if limit := b.maxDifficulty(); difficulty < 0 || difficulty > limit {
+		return fmt.Errorf("difficulty %d out of range [0, %d]", difficulty, limit)
+	}
+	minimum := difficultyWork(difficulty)
+	for i, block := range b.blocks(1) {
+		if block.work().Cmp(minimum) < 0 {
+			return fmt.Errorf("block %d: mined below difficulty %d", i, difficulty)
+		}
+	}
+	b.minDifficulty = difficulty
+	b.difficulty = max(b.difficulty, difficulty)
+	return nil
+}
+
+// This method sets how far ahead of the local clock a block's timestamp may be before
+// Validate rejects it as forged. The default is DefaultMaxClockSkew.
+func (b *Blockchain) SetMaxClockSkew(d time.Duration) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.maxClockSkew = d
+}
+
+// This method enables dynamic difficulty adjustment. When the target is non-zero, the time
+// between each newly mined block and its predecessor is compared against the target after
+// every block. If it was faster, the difficulty is raised by one; if it was slower, the
+// difficulty is lowered by one, but never below 1 or the minimum difficulty (see
+// SetMinDifficulty).
+// A target of zero (the default) disables adjustment and keeps the difficulty fixed.
+// See SetRetargetWindow for a smoother adjustment.
+func (b *Blockchain) SetTargetBlockTime(d time.Duration) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.targetBlockTime = d
+}
+
+// This method makes the difficulty adjustment of SetTargetBlockTime follow the average time
+// between the last n blocks instead of the time of the last block alone, so that a single
+// fast or slow block doesn't change the difficulty. The difficulty is raised by one when the
+// average is more than a quarter below the target block time, and lowered by one (but never
+// below 1 or the minimum difficulty) when it is more than a quarter above it. The average is
+// taken over blocks mined at the current difficulty only, so after every change, n more
+// blocks are mined before the next one. The genesis block never counts, and the block
+// timestamps are used, which Validate guarantees never to decrease.
+// A window of 0 or 1 (the default) restores the adjustment after every block.
+func (b *Blockchain) SetRetargetWindow(n int) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.retargetWindow = n
+}
+
+// How far, as a fraction 1/retargetTolerance of the target block time, the average block time
+// over the retarget window may deviate from the target before the difficulty is adjusted.
+const retargetTolerance = 4
+
+// This method nudges the difficulty towards the target block time, given the block that
+// preceded the one just mined. The caller must hold the exclusive lock.
+func (b *Blockchain) retarget(previousBlock Block) {
+	if b.targetBlockTime <= 0 {
+		return
+	}
+	if b.retargetWindow > 1 {
+		b.retargetWindowed()
+		return
+	}
+	elapsed := b.clock().Sub(previousBlock.timestamp)
+	switch {
+	case elapsed < b.targetBlockTime && b.difficulty < b.maxDifficulty():
+		b.difficulty++
+	case elapsed > b.targetBlockTime && b.difficulty > max(1, b.minDifficulty):
+		b.difficulty--
+	}
+}
+
+// This method implements the adjustment of SetRetargetWindow. The caller must hold the
+// exclusive lock.
+func (b *Blockchain) retargetWindowed() {
+	n := b.retargetWindow
+	first := b.store.Len() - 1 - n
+	if first < 1 {
+		return
+	}
+	start, err := b.store.Get(first)
+	if err != nil {
+		return
+	}
+	var end Block
+	for i := first + 1; i < b.store.Len(); i++ {
+		if end, err = b.store.Get(i); err != nil || end.difficulty != b.difficulty {
+			return
+		}
+	}
+	average := end.timestamp.Sub(start.timestamp) / time.Duration(n)
+	tolerance := b.targetBlockTime / retargetTolerance
+	switch {
+	case average < b.targetBlockTime-tolerance && b.difficulty < b.maxDifficulty():
+		b.difficulty++
+	case average > b.targetBlockTime+tolerance && b.difficulty > max(1, b.minDifficulty):
+		b.difficulty--
+	}
+}
+
+// This method estimates how long mining a block takes at the given difficulty, by mining the
+// given number of throwaway blocks holding dummy data and averaging the wall-clock time. It uses
+// the chain's Miner (see SetMiner) and hash algorithm, so the estimate is representative, but
+// the chain itself is never touched. If a target threshold is set (see SetTargetThreshold),
+// the blocks are mined below it like real blocks, and the difficulty is ignored. The
+// difficulty is clamped like in CreateBlockchain. Zero is returned if samples is not
+// positive, or if mining a block fails.
+func (b *Blockchain) EstimateMineTime(difficulty int, samples int) time.Duration {
+	if samples <= 0 {
+		return 0
+	}
+	b.rlock()
+	newHash, miner, target, startNonce := b.hashFunc(), b.miner(), copyTarget(b.target), b.startNonce
+	b.mu.RUnlock()
+	difficulty = clampDifficulty(difficulty, maxDifficulty(newHash))
+	var total time.Duration
+	for i := 0; i < samples; i++ {
+		transactions := []Transaction{{From: "estimate", To: "estimate", Amount: float64(i), Timestamp: time.Now()}}
+		block := Block{
+			transactions: transactions,
+			previousHash: strconv.Itoa(i),
+			timestamp:    time.Now(),
+			pow:          startNonce,
+			merkleRoot:   merkleRoot(transactions),
+			difficulty:   difficulty,
+			target:       target,
+		}
+		block.setData(nil)
+		block.mining = &miningSession{ctx: context.Background(), newHash: newHash}
+		start := time.Now()
+		if err := miner.Mine(&block, difficulty); err != nil {
+			return 0
 		}
+		total += time.Since(start)
 	}
-	return true
+	return total / time.Duration(samples)
 }
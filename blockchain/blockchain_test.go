@@ -0,0 +1,478 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// This function replaces the block at the given index of the blockchain's MemoryStore with
+// the result of edit, and re-mines it so that its Merkle root, hash and proof of work are
+// consistent again. Only the check under test is then left to catch the tampering. Blocks
+// after the tampered one no longer link to it.
+func tamper(t *testing.T, b *Blockchain, index int, edit func(block *Block)) {
+	t.Helper()
+	store := b.store.(*MemoryStore)
+	block := store.blocks[index]
+	edit(&block)
+	block.merkleRoot = merkleRoot(block.transactions)
+	block.pow = 0
+	if err := block.mine(context.Background(), b.hashFunc()); err != nil {
+		t.Fatal(err)
+	}
+	store.blocks[index] = block
+}
+
+// This function queues the transactions and mines them into a block, failing the test on
+// error.
+func mineTransactions(t *testing.T, b *Blockchain, txs ...Transaction) Block {
+	t.Helper()
+	for _, tx := range txs {
+		if err := b.QueueTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block, err := b.MineBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func TestDifficultyRange(t *testing.T) {
+	tests := []struct {
+		difficulty, want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{3, 3},
+		{1000, MaxDifficulty},
+	}
+	for _, test := range tests {
+		b := CreateBlockchain(test.difficulty)
+		if got := b.Difficulty(); got != test.want {
+			t.Errorf("CreateBlockchain(%d).Difficulty() = %d, want %d", test.difficulty, got, test.want)
+		}
+		_, err := NewBlockchain(test.difficulty)
+		if wantErr := test.difficulty != test.want; (err != nil) != wantErr {
+			t.Errorf("NewBlockchain(%d) = %v, want an error: %v", test.difficulty, err, wantErr)
+		}
+	}
+	b := CreateBlockchain(0)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	if err := b.Validate(); err != nil {
+		t.Fatalf("Validate() at difficulty 0 = %v", err)
+	}
+	if err := b.SetDifficulty(MaxDifficulty + 1); err == nil {
+		t.Fatal("SetDifficulty() accepted a difficulty above MaxDifficulty")
+	}
+}
+
+func TestDifficultyRangeFollowsHashAlgorithm(t *testing.T) {
+	b := CreateBlockchainWithHasher(1000, sha512.New)
+	if got, want := b.Difficulty(), 2*sha512.Size; got != want {
+		t.Fatalf("Difficulty() = %d, want the SHA-512 maximum %d", got, want)
+	}
+	if err := b.SetDifficulty(MaxDifficulty + 1); err != nil {
+		t.Fatalf("SetDifficulty() = %v, want difficulties above the SHA-256 maximum accepted", err)
+	}
+}
+
+func TestHugeDifficultyIsNeverSatisfied(t *testing.T) {
+	block := Block{hash: strings.Repeat("0", 64), difficulty: 1 << 40}
+	if block.proofRule()(block.hash) {
+		t.Fatal("a difficulty above the hash length was satisfied")
+	}
+	if got := block.work(); got.Cmp(difficultyWork(64)) != 0 {
+		t.Fatalf("work() = %v, want at most the work of the hash length", got)
+	}
+}
+
+func TestGenesisDataThatCantBeMarshaled(t *testing.T) {
+	data := map[string]interface{}{"channel": make(chan int)}
+	if _, err := NewBlockchainWithGenesis(1, data, GenesisEpoch()); err == nil {
+		t.Fatal("NewBlockchainWithGenesis() accepted genesis data holding a channel")
+	}
+	b := CreateBlockchainWithGenesis(1, data, GenesisEpoch())
+	if err := b.Validate(); err == nil {
+		t.Fatal("Validate() accepted a genesis block without a hash")
+	}
+	if _, err := NewBlockchainWithGenesis(1, map[string]interface{}{"network": "test"}, GenesisEpoch()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddDataRejectsDataThatCantBeMarshaled(t *testing.T) {
+	b := CreateBlockchain(1)
+	if _, err := b.AddData(map[string]interface{}{"channel": make(chan int)}); err == nil {
+		t.Fatal("AddData() accepted a payload holding a channel")
+	}
+	if got := b.Len(); got != 1 {
+		t.Fatalf("Len() after a rejected payload = %d, want 1", got)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedGenesisBlock(t *testing.T) {
+	data := map[string]interface{}{"network": "test", "params": map[string]interface{}{"b": 2, "a": 1}}
+	first := CreateBlockchainWithGenesis(2, data, GenesisEpoch())
+	second := CreateBlockchainWithGenesis(2, data, GenesisEpoch())
+	if first.genesisBlock.Hash() != second.genesisBlock.Hash() {
+		t.Fatal("two chains created with the same genesis parameters have different genesis blocks")
+	}
+	other := CreateBlockchainWithGenesis(2, data, GenesisEpoch().Add(time.Second))
+	if other.genesisBlock.Hash() == first.genesisBlock.Hash() {
+		t.Fatal("a different genesis time produced the same genesis block")
+	}
+}
+
+func TestRecordedDifficultyIsCoveredByHash(t *testing.T) {
+	for _, difficulty := range []int{1, 5} {
+		b := CreateBlockchain(2)
+		mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+		b.store.(*MemoryStore).blocks[1].difficulty = difficulty
+		if err := b.Validate(); !errors.Is(err, ErrChainTampered) {
+			t.Errorf("Validate() with difficulty 2 recorded as %d = %v, want ErrChainTampered", difficulty, err)
+		}
+		diagnostics := b.Diagnose()
+		if len(diagnostics) != 1 || diagnostics[0].Problems[0] != ProblemHashMismatch {
+			t.Errorf("Diagnose() with difficulty 2 recorded as %d = %v, want a hash mismatch in block 1", difficulty, diagnostics)
+		}
+	}
+}
+
+// This function builds the chain of the reproducible test vector: a genesis block and one
+// block recording a transfer from alice to bob.
+func testVectorChain(t *testing.T) Blockchain {
+	t.Helper()
+	b := CreateTestVectorBlockchain(2, map[string]interface{}{"name": "test vector"})
+	if err := b.AddTransaction("alice", "bob", 1); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTestVectorBlockchainIsReproducible(t *testing.T) {
+	first, second := testVectorChain(t), testVectorChain(t)
+	if first.ChainHash() != second.ChainHash() {
+		t.Fatalf("two test vector chains differ: %v and %v", first.Blocks(), second.Blocks())
+	}
+	// Pinned, so that a change to the hash preimage or to the test vector option shows up.
+	const (
+		genesisHash = "0175c1c3772824a64d2826cd109ae3a39efaf281b7fea4cbbe1bb68eef8064e7"
+		tipHash     = "0021edf1bd62fb7b9a362ce80095d56b74523fbd3ff28b8a7510570ff93e07c3"
+	)
+	if got := first.Blocks()[0].Hash(); got != genesisHash {
+		t.Errorf("genesis hash = %s, want %s", got, genesisHash)
+	}
+	if got := first.LatestBlock().Hash(); got != tipHash {
+		t.Errorf("tip hash = %s, want %s", got, tipHash)
+	}
+}
+
+func TestRetargetWindow(t *testing.T) {
+	b := CreateBlockchain(1)
+	now := time.Now()
+	b.SetClock(func() time.Time { return now })
+	b.SetTargetBlockTime(time.Minute)
+	b.SetRetargetWindow(5)
+	// Blocks are mined every second, far faster than the target block time.
+	for i := 0; i < 6; i++ {
+		if got := b.Difficulty(); got != 1 {
+			t.Fatalf("difficulty after %d fast blocks = %d, want 1 until the window is full", i, got)
+		}
+		now = now.Add(time.Second)
+		mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1, Nonce: uint64(i)})
+	}
+	if got := b.Difficulty(); got != 2 {
+		t.Fatalf("difficulty after a window of fast blocks = %d, want 2", got)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// This test is meant to be run with the race detector: go test -race.
+func TestConcurrentAddTransaction(t *testing.T) {
+	b := CreateBlockchain(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.AddTransaction("alice", fmt.Sprintf("account%d", i), 1); err != nil {
+				t.Error(err)
+			}
+			b.BalanceOf("alice")
+			b.IsValid()
+		}()
+	}
+	wg.Wait()
+	if got := b.Len(); got != 51 {
+		t.Fatalf("Len() = %d, want 51", got)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.BalanceOf("alice"); got != -50 {
+		t.Fatalf("BalanceOf(alice) = %v, want -50", got)
+	}
+}
+
+func TestCopiesShareTheChain(t *testing.T) {
+	c := CreateBlockchain(1)
+	c.EnableUTXOIndex()
+	d := c
+	tx := Transaction{From: "alice", To: "bob", Amount: 5, Timestamp: GenesisEpoch()}
+	if err := d.SubmitTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Len(); got != 2 {
+		t.Fatalf("Len() of the copy's source = %d, want 2", got)
+	}
+	if got := c.BalanceOf("bob"); got != 5 {
+		t.Fatalf("BalanceOf(bob) on the copy's source = %v, want 5", got)
+	}
+	if err := c.SubmitTransaction(tx); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("SubmitTransaction() of the copy's transaction = %v, want ErrDuplicateTransaction", err)
+	}
+}
+
+func TestValidateChecksGenesisBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(genesis *Block)
+	}{
+		{"timestamp", func(genesis *Block) {
+			genesis.timestamp = genesis.timestamp.Add(time.Second)
+		}},
+		{"data", func(genesis *Block) {
+			genesis.setData(map[string]interface{}{"note": "rewritten"})
+		}},
+		{"replaced", func(genesis *Block) {
+			*genesis = newGenesisBlock(map[string]interface{}{"note": "rewritten"}, genesis.timestamp, sha256.New)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := CreateBlockchainWithGenesis(1, map[string]interface{}{"note": "genesis"}, GenesisEpoch())
+			test.edit(&b.store.(*MemoryStore).blocks[0])
+			if err := b.Validate(); !errors.Is(err, ErrChainTampered) {
+				t.Fatalf("Validate() = %v, want ErrChainTampered", err)
+			}
+		})
+	}
+}
+
+// This benchmark validates a 10k-block chain of data blocks with the marshaled data cached on
+// the blocks, as usual, and with the cache dropped, so that every data is marshaled again.
+func BenchmarkIsValid(b *testing.B) {
+	chain := CreateBlockchainWithGenesis(0, nil, GenesisEpoch())
+	epoch := GenesisEpoch()
+	chain.SetClock(func() time.Time { return epoch })
+	for i := 0; i < 10000; i++ {
+		payload := map[string]interface{}{"sensor": fmt.Sprintf("s%d", i%10), "reading": i, "tags": []interface{}{"a", "b"}}
+		if _, err := chain.AddData(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+	uncached := chain.Clone()
+	blocks := uncached.store.(*MemoryStore).blocks
+	for i := range blocks {
+		blocks[i].dataJSON = nil
+	}
+	for _, bench := range []struct {
+		name  string
+		chain *Blockchain
+	}{
+		{"cached", &chain},
+		{"uncached", &uncached},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !bench.chain.IsValid() {
+					b.Fatal("IsValid() = false")
+				}
+			}
+		})
+	}
+}
+
+func TestZeroValueBlockchain(t *testing.T) {
+	dir := t.TempDir()
+	tx := Transaction{From: "alice", To: "bob", Amount: 1}
+	other := CreateBlockchain(0)
+	block := other.LatestBlock()
+	now := time.Now()
+	methods := map[string]func(b *Blockchain){
+		"EnableAccountIndex":    func(b *Blockchain) { b.EnableAccountIndex() },
+		"EnableUTXOIndex":       func(b *Blockchain) { b.EnableUTXOIndex() },
+		"AddTransaction":        func(b *Blockchain) { b.AddTransaction("alice", "bob", 1) },
+		"AddTransactionContext": func(b *Blockchain) { b.AddTransactionContext(context.Background(), "alice", "bob", 1) },
+		"SubmitTransaction":     func(b *Blockchain) { b.SubmitTransaction(tx) },
+		"SubmitTransactionContext": func(b *Blockchain) {
+			b.SubmitTransactionContext(context.Background(), tx)
+		},
+		"AddTransactionBlock":    func(b *Blockchain) { b.AddTransactionBlock("alice", "bob", 1) },
+		"AddData":                func(b *Blockchain) { b.AddData(map[string]interface{}{"k": "v"}) },
+		"MineEmptyBlock":         func(b *Blockchain) { b.MineEmptyBlock() },
+		"Validate":               func(b *Blockchain) { b.Validate() },
+		"ValidateContext":        func(b *Blockchain) { b.ValidateContext(context.Background()) },
+		"IsValid":                func(b *Blockchain) { b.IsValid() },
+		"ValidateTip":            func(b *Blockchain) { b.ValidateTip() },
+		"Blocks":                 func(b *Blockchain) { b.Blocks() },
+		"Len":                    func(b *Blockchain) { b.Len() },
+		"BlockAt":                func(b *Blockchain) { b.BlockAt(0) },
+		"LatestBlock":            func(b *Blockchain) { b.LatestBlock() },
+		"BalanceOf":              func(b *Blockchain) { b.BalanceOf("alice") },
+		"CanAfford":              func(b *Blockchain) { b.CanAfford("alice", 1) },
+		"CanAffordWithFee":       func(b *Blockchain) { b.CanAffordWithFee("alice", 1, 0.1) },
+		"BalanceOfChecked":       func(b *Blockchain) { b.BalanceOfChecked("alice") },
+		"SetMiningReward":        func(b *Blockchain) { b.SetMiningReward(1) },
+		"SetMinerAddress":        func(b *Blockchain) { b.SetMinerAddress("miner") },
+		"SetClock":               func(b *Blockchain) { b.SetClock(time.Now) },
+		"SetStartNonce":          func(b *Blockchain) { b.SetStartNonce(1) },
+		"SetTargetThreshold":     func(b *Blockchain) { b.SetTargetThreshold(nil) },
+		"Difficulty":             func(b *Blockchain) { b.Difficulty() },
+		"SetDifficulty":          func(b *Blockchain) { b.SetDifficulty(1) },
+		"SetMinDifficulty":       func(b *Blockchain) { b.SetMinDifficulty(1) },
+		"SetMaxClockSkew":        func(b *Blockchain) { b.SetMaxClockSkew(time.Minute) },
+		"SetTargetBlockTime":     func(b *Blockchain) { b.SetTargetBlockTime(time.Second) },
+		"SetRetargetWindow":      func(b *Blockchain) { b.SetRetargetWindow(5) },
+		"EstimateMineTime":       func(b *Blockchain) { b.EstimateMineTime(0, 1) },
+		"Clone":                  func(b *Blockchain) { b.Clone() },
+		"Snapshot":               func(b *Blockchain) { b.Snapshot() },
+		"ReplaceChain":           func(b *Blockchain) { b.ReplaceChain([]Block{block}) },
+		"TruncateAfter":          func(b *Blockchain) { b.TruncateAfter(0) },
+		"Finalize":               func(b *Blockchain) { b.Finalize(0) },
+		"FinalizedHeight":        func(b *Blockchain) { b.FinalizedHeight() },
+		"TotalWork":              func(b *Blockchain) { b.TotalWork() },
+		"CompareTo":              func(b *Blockchain) { b.CompareTo(Blockchain{}) },
+		"ChainHash":              func(b *Blockchain) { b.ChainHash() },
+		"Diagnose":               func(b *Blockchain) { b.Diagnose() },
+		"OnBlockMined":           func(b *Blockchain) { b.OnBlockMined(func(Block) {}) },
+		"OnTransactionRejected":  func(b *Blockchain) { b.OnTransactionRejected(func(string, string, float64, error) {}) },
+		"SetLogger":              func(b *Blockchain) { b.SetLogger(func(string, map[string]interface{}) {}) },
+		"ExportTransactionsCSV":  func(b *Blockchain) { b.ExportTransactionsCSV(&bytes.Buffer{}) },
+		"AddBlock":               func(b *Blockchain) { b.AddBlock(block) },
+		"ValidateCandidateBlock": func(b *Blockchain) { b.ValidateCandidateBlock(block) },
+		"AppendMinedBlock":       func(b *Blockchain) { b.AppendMinedBlock(block) },
+		"OrphanBlocks":           func(b *Blockchain) { b.OrphanBlocks() },
+		"String":                 func(b *Blockchain) { _ = b.String() },
+		"AuditString":            func(b *Blockchain) { _ = b.AuditString() },
+		"EncodeGob":              func(b *Blockchain) { b.EncodeGob(&bytes.Buffer{}) },
+		"HTTPHandler": func(b *Blockchain) {
+			b.HTTPHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/blocks", nil))
+		},
+		"QueueTransaction":           func(b *Blockchain) { b.QueueTransaction(tx) },
+		"PendingTransactions":        func(b *Blockchain) { b.PendingTransactions() },
+		"MineBlock":                  func(b *Blockchain) { b.MineBlock() },
+		"MineBlockContext":           func(b *Blockchain) { b.MineBlockContext(context.Background()) },
+		"SetMaxTransactionsPerBlock": func(b *Blockchain) { b.SetMaxTransactionsPerBlock(2) },
+		"SetMempoolTTL":              func(b *Blockchain) { b.SetMempoolTTL(time.Minute) },
+		"ExpireMempool":              func(b *Blockchain) { b.ExpireMempool() },
+		"SaveMempool":                func(b *Blockchain) { b.SaveMempool(&bytes.Buffer{}) },
+		"LoadMempool":                func(b *Blockchain) { b.LoadMempool(strings.NewReader("[]")) },
+		"VerifyBlockData":            func(b *Blockchain) { b.VerifyBlockData(0) },
+		"Metrics":                    func(b *Blockchain) { b.Metrics() },
+		"ApproxMemoryBytes":          func(b *Blockchain) { b.ApproxMemoryBytes() },
+		"SetMiner":                   func(b *Blockchain) { b.SetMiner(nil) },
+		"SaveToFile":                 func(b *Blockchain) { b.SaveToFile(filepath.Join(dir, "chain.json")) },
+		"SaveCompressed":             func(b *Blockchain) { b.SaveCompressed(filepath.Join(dir, "chain.json.gz")) },
+		"SaveStreaming":              func(b *Blockchain) { b.SaveStreaming(&bytes.Buffer{}) },
+		"BalanceProofAt":             func(b *Blockchain) { b.BalanceProofAt("alice", 0) },
+		"BalanceAtHeight":            func(b *Blockchain) { b.BalanceAtHeight("alice", 0) },
+		"TransactionsFor":            func(b *Blockchain) { b.TransactionsFor("alice") },
+		"TransactionsBetween":        func(b *Blockchain) { b.TransactionsBetween("alice", "bob") },
+		"BlocksBetween":              func(b *Blockchain) { b.BlocksBetween(now.Add(-time.Hour), now) },
+		"All": func(b *Blockchain) {
+			for range b.All() {
+			}
+		},
+		"ContainsTransaction": func(b *Blockchain) { b.ContainsTransaction(tx) },
+		"FindBlockByHash":     func(b *Blockchain) { b.FindBlockByHash(block.Hash()) },
+		"LastActivity":        func(b *Blockchain) { b.LastActivity("alice") },
+		"Confirmations":       func(b *Blockchain) { b.Confirmations(block.Hash()) },
+		"GetRange":            func(b *Blockchain) { b.GetRange(0, 0) },
+		"ReplayOnto":          func(b *Blockchain) { b.ReplayOnto(0) },
+		"Stats":               func(b *Blockchain) { b.Stats() },
+		"TopBalances":         func(b *Blockchain) { b.TopBalances(3) },
+		"ConservesValue":      func(b *Blockchain) { b.ConservesValue() },
+		"CheckConservation":   func(b *Blockchain) { b.CheckConservation() },
+		"SyncWith": func(b *Blockchain) {
+			ours, theirs := net.Pipe()
+			theirs.Close()
+			b.SyncWith(ours)
+		},
+		"SetStrictMode": func(b *Blockchain) { b.SetStrictMode(true) },
+	}
+	for name, method := range methods {
+		t.Run(name, func(t *testing.T) {
+			b := &Blockchain{}
+			method(b)
+			if b.Len() < 1 || !b.IsValid() {
+				t.Fatalf("after %s, the zero-value blockchain holds %d blocks and is valid: %v", name, b.Len(), b.IsValid())
+			}
+		})
+	}
+	if got := (Blockchain{}).String(); got != "(empty blockchain)\n" {
+		t.Errorf("Blockchain{}.String() = %q", got)
+	}
+}
+
+func TestMiningAcrossDifficultyChange(t *testing.T) {
+	b := CreateBlockchain(1)
+	before := mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	if err := b.SetDifficulty(3); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Difficulty(); got != 3 {
+		t.Fatalf("Difficulty() = %d after SetDifficulty(3)", got)
+	}
+	after := mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	if before.Difficulty() != 1 || after.Difficulty() != 3 || !strings.HasPrefix(after.Hash(), "000") {
+		t.Fatalf("blocks mined at difficulties %d and %d, want 1 and 3", before.Difficulty(), after.Difficulty())
+	}
+	if err := b.SetDifficulty(2); err != nil {
+		t.Fatal(err)
+	}
+	mineTransactions(t, &b, Transaction{From: "carol", To: "dave", Amount: 1})
+	if err := b.Validate(); err != nil {
+		t.Fatalf("Validate() of blocks mined at several difficulties = %v", err)
+	}
+	if err := b.SetDifficulty(-1); err == nil {
+		t.Fatal("SetDifficulty() accepted a negative difficulty")
+	}
+}
+
+func TestValidateRejectsBackwardDatedBlock(t *testing.T) {
+	b := CreateBlockchain(1)
+	now := time.Now()
+	b.SetClock(func() time.Time { return now })
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	// Blocks mined within the same instant are fine.
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	if err := b.Validate(); err != nil {
+		t.Fatalf("Validate() of blocks with equal timestamps = %v", err)
+	}
+	tamper(t, &b, 2, func(block *Block) {
+		block.timestamp = now.Add(-time.Second)
+	})
+	err := b.Validate()
+	if !errors.Is(err, ErrChainTampered) || !strings.Contains(err.Error(), "earlier than block 1") {
+		t.Fatalf("Validate() = %v, want a timestamp error for block 2", err)
+	}
+}
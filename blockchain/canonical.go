@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// This function returns the canonical serialization of block data that calculateHash hashes:
+// compact JSON in which the keys of every object, at any depth, are sorted. Scalars are
+// encoded exactly as encoding/json encodes them, so the output matches json.Marshal for the
+// plain maps, slices and scalars that block data decodes to. Values of other types, such as
+// structs, are first converted to that plain form through JSON, which makes the bytes
+// independent of struct field order and map insertion order.
+func canonicalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// This function appends the canonical serialization of v to buf.
+func writeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			buf.WriteString("null")
+			return nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		if v == nil {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case nil, string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+		return nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var plain interface{}
+		if err := json.Unmarshal(encoded, &plain); err != nil {
+			return err
+		}
+		return writeCanonical(buf, plain)
+	}
+}
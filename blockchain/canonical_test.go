@@ -0,0 +1,51 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestCanonicalJSONIgnoresInsertionOrder(t *testing.T) {
+	first := map[string]interface{}{}
+	first["zeta"] = 1
+	first["alpha"] = map[string]interface{}{"y": true, "b": []interface{}{map[string]interface{}{"d": 1, "c": 2}}}
+	first["mid"] = "x"
+
+	nested := map[string]interface{}{}
+	nested["b"] = []interface{}{map[string]interface{}{"c": 2, "d": 1}}
+	nested["y"] = true
+	second := map[string]interface{}{}
+	second["mid"] = "x"
+	second["alpha"] = nested
+	second["zeta"] = 1
+
+	// A struct, and a map of another type, whose fields and keys come in another order.
+	third := map[string]interface{}{
+		"alpha": struct {
+			Y bool             `json:"y"`
+			B []map[string]int `json:"b"`
+		}{true, []map[string]int{{"d": 1, "c": 2}}},
+		"zeta": 1,
+		"mid":  "x",
+	}
+
+	const want = `{"alpha":{"b":[{"c":2,"d":1}],"y":true},"mid":"x","zeta":1}`
+	var hashes []string
+	for _, data := range []map[string]interface{}{first, second, third} {
+		got, err := canonicalJSON(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("canonicalJSON() = %s, want %s", got, want)
+		}
+		block := Block{timestamp: GenesisEpoch()}
+		if err := block.setData(data); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, block.calculateHash(sha256.New))
+	}
+	if hashes[0] != hashes[1] || hashes[1] != hashes[2] {
+		t.Fatalf("the same data hashes to %v", hashes)
+	}
+}
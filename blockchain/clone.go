@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"math/big"
+	"time"
+)
+
+// This method returns an independent deep copy of the blockchain, for example to try a
+// speculative reorganization without risking the original. The blocks, their data and
+// transactions, the mempool, the side branches and all settings are copied, so changes to the
+// clone never affect the source and vice versa. The clone keeps its blocks in memory, even if
+// the source uses another BlockStore, and has no OnBlockMined or OnTransactionRejected
+// callbacks; the logger is shared.
+// If the source's block store fails, the clone holds only the blocks read before the
+// failure; use Validate on the source to find out why.
+func (b *Blockchain) Clone() Blockchain {
+	b.rlock()
+	defer b.mu.RUnlock()
+	clone, _ := b.clone()
+	return clone
+}
+
+// This method returns a consistent point-in-time copy of the blockchain for serving reads, for
+// example to encode the whole chain in an HTTP handler without holding the lock during I/O, so
+// that slow clients neither block the miner nor see a chain torn by a concurrent append. The
+// copy is taken under the lock and is as deep as with Clone, so it doesn't change when the
+// source does: the snapshot does not receive blocks mined or added after it was taken.
+// Callers should treat it as read-only. If the source's block store fails, the snapshot holds
+// only the blocks read before the failure.
+func (b *Blockchain) Snapshot() Blockchain {
+	b.rlock()
+	defer b.mu.RUnlock()
+	snapshot, _ := b.clone()
+	return snapshot
+}
+
+// This method implements Clone without taking the lock. On error, the returned copy holds the
+// blocks read up to the failure.
+func (b *Blockchain) clone() (Blockchain, error) {
+	blocks, err := b.collect()
+	for i := range blocks {
+		blocks[i] = blocks[i].clone()
+	}
+	var sideBlocks map[string]Block
+	if b.sideBlocks != nil {
+		sideBlocks = make(map[string]Block, len(b.sideBlocks))
+		for hash, block := range b.sideBlocks {
+			sideBlocks[hash] = block.clone()
+		}
+	}
+	return Blockchain{&chainState{
+		genesisBlock:    b.genesisBlock.clone(),
+		store:           &MemoryStore{blocks: blocks},
+		difficulty:      b.difficulty,
+		targetBlockTime: b.targetBlockTime,
+		retargetWindow:  b.retargetWindow,
+		minDifficulty:   b.minDifficulty,
+		miningReward:    b.miningReward,
+		minerAddress:    b.minerAddress,
+		logger:          b.logger,
+		pending:         append([]Transaction(nil), b.pending...),
+		queuedAt:        append([]time.Time(nil), b.queuedAt...),
+		mempoolTTL:      b.mempoolTTL,
+		maxBlockTxs:     b.maxBlockTxs,
+		sideBlocks:      sideBlocks,
+		now:             b.now,
+		hasher:          b.hasher,
+		target:          copyTarget(b.target),
+		startNonce:      b.startNonce,
+		maxClockSkew:    b.maxClockSkew,
+		finalized:       b.finalized,
+		strict:          b.strict,
+		utxo:            b.utxo.clone(),
+		accounts:        b.accounts.clone(),
+		mining:          b.mining,
+	}}, err
+}
+
+// This method returns a deep copy of the block that shares no maps or slices with it.
+func (b Block) clone() Block {
+	b.data = copyData(b.data)
+	b.dataJSON = append([]byte(nil), b.dataJSON...)
+	b.transactions = b.Transactions()
+	b.target = copyTarget(b.target)
+	return b
+}
+
+// This function returns a copy of a target threshold, or nil.
+func copyTarget(target *big.Int) *big.Int {
+	if target == nil {
+		return nil
+	}
+	return new(big.Int).Set(target)
+}
@@ -0,0 +1,38 @@
+package blockchain
+
+import "testing"
+
+func TestCloneIsIndependent(t *testing.T) {
+	b := CreateBlockchainWithGenesis(1, map[string]interface{}{"nested": map[string]interface{}{"k": "v"}}, GenesisEpoch())
+	b.EnableUTXOIndex()
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	if err := b.QueueTransaction(Transaction{From: "bob", To: "carol", Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	clone := b.Clone()
+	if _, err := clone.MineBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clone.AddData(map[string]interface{}{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+	blocks := clone.store.(*MemoryStore).blocks
+	blocks[0].data["nested"].(map[string]interface{})["k"] = "changed"
+	blocks[1].transactions[0].Amount = 1000
+
+	if got := b.Len(); got != 2 {
+		t.Fatalf("Len() of the original = %d after appending to the clone, want 2", got)
+	}
+	if got := clone.Len(); got != 4 {
+		t.Fatalf("Len() of the clone = %d, want 4", got)
+	}
+	if got := len(b.PendingTransactions()); got != 1 {
+		t.Fatalf("the original has %d queued transactions after the clone mined them, want 1", got)
+	}
+	if got := b.BalanceOf("carol"); got != 0 {
+		t.Fatalf("BalanceOf(carol) in the original = %v, want 0", got)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatalf("Validate() of the original after changing the clone's blocks = %v", err)
+	}
+}
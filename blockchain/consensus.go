@@ -0,0 +1,220 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// This method implements the "heaviest valid chain" consensus rule. The candidate, typically
+// another node's chain, replaces the local chain only if it is internally valid, starts with
+// our genesis block, and represents strictly more total work than the local chain (see
+// TotalWork). When difficulty varies, a shorter chain mined at a higher difficulty can
+// therefore win over a longer one. A candidate that differs from the local chain at or below
+// the finalized height (see Finalize) is rejected too.
+// It returns whether the chain was replaced, and an error describing why the candidate was
+// rejected. The local chain is left untouched on rejection.
+func (b *Blockchain) ReplaceChain(candidate []Block) (bool, error) {
+	b.lock()
+	defer b.mu.Unlock()
+	if len(candidate) == 0 {
+		return false, errors.New("candidate chain is empty")
+	}
+	if candidate[0].hash != b.genesisBlock.hash {
+		return false, errors.New("candidate chain does not share our genesis block")
+	}
+	ours, err := b.totalWork()
+	if err != nil {
+		return false, err
+	}
+	if theirs := totalWork(candidate); theirs.Cmp(ours) <= 0 {
+		return false, fmt.Errorf("candidate chain with total work %s does not exceed ours (%s)", theirs, ours)
+	}
+	if len(candidate) <= b.finalized {
+		return false, fmt.Errorf("candidate chain drops finalized block %d", b.finalized)
+	}
+	for i := 1; i <= b.finalized; i++ {
+		ours, err := b.store.Get(i)
+		if err != nil {
+			return false, err
+		}
+		if candidate[i].hash != ours.hash {
+			return false, fmt.Errorf("candidate chain rewrites finalized block %d", i)
+		}
+	}
+	chain := make([]Block, len(candidate))
+	copy(chain, candidate)
+	// The candidate is validated under every setting of ours that validation reads.
+	replacement := Blockchain{&chainState{
+		genesisBlock:  b.genesisBlock,
+		store:         &MemoryStore{blocks: chain},
+		difficulty:    b.difficulty,
+		minDifficulty: b.minDifficulty,
+		miningReward:  b.miningReward,
+		now:           b.now,
+		hasher:        b.hasher,
+		maxClockSkew:  b.maxClockSkew,
+	}}
+	if err := replacement.validate(); err != nil {
+		return false, fmt.Errorf("candidate chain is not valid: %w", err)
+	}
+	if err := b.replaceBlocks(chain); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// This method drops all blocks after the given index, so that the block at index becomes the
+// tip. The genesis block and finalized blocks (see Finalize) can't be dropped, so an error is
+// returned for an index below the finalized height or out of range, or if the block store
+// doesn't support truncation.
+// This supports reorg experiments and recovering from corruption detected at a known-good height.
+func (b *Blockchain) TruncateAfter(index int) error {
+	b.lock()
+	defer b.mu.Unlock()
+	if index < 1 || index >= b.store.Len() {
+		return fmt.Errorf("cannot truncate after block %d: index out of range [1, %d]", index, b.store.Len()-1)
+	}
+	if index < b.finalized {
+		return fmt.Errorf("cannot truncate after block %d: block %d is finalized", index, b.finalized)
+	}
+	store, ok := b.store.(truncatableStore)
+	if !ok {
+		return errors.New("block store does not support truncation")
+	}
+	if err := store.Truncate(index + 1); err != nil {
+		return err
+	}
+	b.rebuildIndex()
+	return nil
+}
+
+// This method marks the blocks up to and including the given index as final. Final blocks can
+// no longer be replaced: ReplaceChain and AddBlock refuse candidates that would rewrite
+// history at or below the finalized height, and TruncateAfter refuses to drop them. This
+// protects against deep reorganizations and long-range attacks.
+// Finality only ever advances, so finalizing below the current finalized height has no effect.
+// An error is returned if the index is out of range. The finalized height is saved by
+// SaveToFile and EncodeGob.
+func (b *Blockchain) Finalize(upToIndex int) error {
+	b.lock()
+	defer b.mu.Unlock()
+	if upToIndex < 0 || upToIndex >= b.store.Len() {
+		return fmt.Errorf("cannot finalize block %d: index out of range [0, %d]", upToIndex, b.store.Len()-1)
+	}
+	if upToIndex > b.finalized {
+		b.finalized = upToIndex
+	}
+	return nil
+}
+
+// This method returns the index of the last finalized block, or 0 if only the genesis block
+// is final.
+func (b *Blockchain) FinalizedHeight() int {
+	b.rlock()
+	defer b.mu.RUnlock()
+	return b.finalized
+}
+
+// This method returns the cumulative proof of work of the chain: the sum of the expected
+// number of hash attempts needed to mine each block after the genesis block. A block mined at
+// difficulty d counts 16^d attempts; a block mined below a target threshold counts the size
+// of the hash space divided by the target. ReplaceChain prefers the chain with more total work.
+func (b *Blockchain) TotalWork() *big.Int {
+	b.rlock()
+	defer b.mu.RUnlock()
+	work, _ := b.totalWork()
+	return work
+}
+
+// This method implements TotalWork without taking the lock.
+func (b *Blockchain) totalWork() (*big.Int, error) {
+	total := new(big.Int)
+	for i := 1; i < b.store.Len(); i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return total, err
+		}
+		total.Add(total, block.work())
+	}
+	return total, nil
+}
+
+// This function returns the cumulative proof of work of the blocks after the first one.
+func totalWork(blocks []Block) *big.Int {
+	total := new(big.Int)
+	for i := 1; i < len(blocks); i++ {
+		total.Add(total, blocks[i].work())
+	}
+	return total
+}
+
+// This method returns the expected number of hash attempts needed to mine the block, which
+// is at least 1.
+func (b Block) work() *big.Int {
+	if b.target != nil && b.target.Sign() > 0 {
+		space := new(big.Int).Lsh(big.NewInt(1), uint(4*len(b.hash)))
+		work := space.Quo(space, b.target)
+		if work.Sign() == 0 {
+			work.SetInt64(1)
+		}
+		return work
+	}
+	// A hash can't have more leading zeros than it has characters.
+	return difficultyWork(clampDifficulty(b.difficulty, len(b.hash)))
+}
+
+// This function returns the expected number of hash attempts needed to mine a block at the
+// given difficulty.
+func difficultyWork(difficulty int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(16), big.NewInt(int64(difficulty)), nil)
+}
+
+// This method compares the chain with another one block by block, by hash, to diagnose why two
+// nodes disagree and what to sync. It returns the index of the first position at which the
+// chains differ, and whether they are identical up to the length of the shorter chain. If
+// one chain is a strict prefix of the other, or both are identical, the fork point is the
+// length of the shorter chain and equal is true; the longer chain's blocks from the fork point
+// on are what the other node is missing. The other chain is read before this one is locked, so
+// comparing a chain with itself or a copy of itself is safe.
+func (b *Blockchain) CompareTo(other Blockchain) (forkPoint int, equal bool) {
+	theirs := other.Blocks()
+	b.rlock()
+	defer b.mu.RUnlock()
+	n := min(len(theirs), b.store.Len())
+	for i := 0; i < n; i++ {
+		ours, err := b.store.Get(i)
+		if err != nil || ours.hash != theirs[i].hash {
+			return i, false
+		}
+	}
+	return n, true
+}
+
+// This method returns a single digest representing the whole chain: the hex hash, with the
+// chain's hash algorithm, of the concatenated hashes of all blocks, starting with the genesis
+// block. Because every block hash covers the block's content and its predecessor, two chains
+// have the same chain hash exactly when they hold the same blocks, and any modification
+// anywhere changes it. Comparing chain hashes is cheaper than walking two chains, and the
+// chain hash serves as the ETag of GET /blocks (see HTTPHandler). The empty string is
+// returned if the block store fails.
+func (b *Blockchain) ChainHash() string {
+	b.rlock()
+	defer b.mu.RUnlock()
+	return b.chainHash()
+}
+
+// This method implements ChainHash without taking the lock.
+func (b *Blockchain) chainHash() string {
+	hasher := b.hashFunc()()
+	for i := 0; i < b.store.Len(); i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return ""
+		}
+		io.WriteString(hasher, block.hash)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplaceChainUsesOurSettings(t *testing.T) {
+	// Blocks stamped by a clock far ahead of the real one, and paying a mining reward, are
+	// only valid under the local chain's clock and reward.
+	future := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := CreateBlockchainWithGenesis(1, nil, GenesisEpoch())
+	b.SetClock(func() time.Time { return future })
+	b.SetMiningReward(10)
+	b.SetMinerAddress("miner")
+	peer := b.Clone()
+	mineTransactions(t, &peer, Transaction{From: "alice", To: "bob", Amount: 1})
+	replaced, err := b.ReplaceChain(peer.Blocks())
+	if err != nil || !replaced {
+		t.Fatalf("ReplaceChain() = %v, %v, want the heavier chain adopted", replaced, err)
+	}
+	if got := b.BalanceOf("miner"); got != 10 {
+		t.Fatalf("BalanceOf(miner) = %v, want 10", got)
+	}
+}
+
+func TestChainHash(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	clone := b.Clone()
+	original := b.ChainHash()
+	if original == "" || clone.ChainHash() != original {
+		t.Fatalf("ChainHash() of a clone = %q, want %q", clone.ChainHash(), original)
+	}
+	mineTransactions(t, &clone, Transaction{From: "carol", To: "dave", Amount: 1})
+	if clone.ChainHash() == original {
+		t.Fatal("ChainHash() didn't change when a block was appended")
+	}
+	tamper(t, &b, 1, func(block *Block) {
+		block.transactions[0].Amount = 2
+	})
+	if b.ChainHash() == original {
+		t.Fatal("ChainHash() didn't change when a block was tampered with")
+	}
+}
+
+func TestReplaceChainPrefersWorkOverLength(t *testing.T) {
+	b := CreateBlockchainWithGenesis(1, nil, GenesisEpoch())
+	heavy := b.Clone()
+	for i := 0; i < 3; i++ {
+		mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1, Nonce: uint64(i)})
+	}
+	if err := heavy.SetDifficulty(3); err != nil {
+		t.Fatal(err)
+	}
+	mineTransactions(t, &heavy, Transaction{From: "alice", To: "carol", Amount: 1})
+	if got := b.TotalWork().Int64(); got != 3*16 {
+		t.Fatalf("TotalWork() of three blocks at difficulty 1 = %d, want %d", got, 3*16)
+	}
+	if got := heavy.TotalWork().Int64(); got != 16*16*16 {
+		t.Fatalf("TotalWork() of one block at difficulty 3 = %d, want %d", got, 16*16*16)
+	}
+
+	light := b.Clone()
+	if replaced, err := heavy.ReplaceChain(light.Blocks()); replaced || err == nil {
+		t.Fatalf("ReplaceChain() of a longer but lighter chain = %v, %v, want it rejected", replaced, err)
+	}
+	if replaced, err := b.ReplaceChain(heavy.Blocks()); !replaced || err != nil {
+		t.Fatalf("ReplaceChain() of a shorter but heavier chain = %v, %v, want it adopted", replaced, err)
+	}
+	if b.Len() != 2 || b.LatestBlock().Hash() != heavy.LatestBlock().Hash() {
+		t.Fatalf("chain holds %d blocks after ReplaceChain, want the 2 blocks of the heavier chain", b.Len())
+	}
+}
+
+func TestCompareTo(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	peer := b.Clone()
+	if forkPoint, equal := b.CompareTo(peer); forkPoint != 2 || !equal {
+		t.Fatalf("CompareTo(clone) = %d, %v, want 2, true", forkPoint, equal)
+	}
+	mineTransactions(t, &b, Transaction{From: "alice", To: "carol", Amount: 1})
+	if forkPoint, equal := b.CompareTo(peer); forkPoint != 2 || !equal {
+		t.Fatalf("CompareTo(prefix) = %d, %v, want 2, true", forkPoint, equal)
+	}
+	mineTransactions(t, &peer, Transaction{From: "alice", To: "dave", Amount: 1})
+	if forkPoint, equal := b.CompareTo(peer); forkPoint != 2 || equal {
+		t.Fatalf("CompareTo(fork) = %d, %v, want 2, false", forkPoint, equal)
+	}
+	if forkPoint, equal := b.CompareTo(b); forkPoint != 3 || !equal {
+		t.Fatalf("CompareTo(itself) = %d, %v, want 3, true", forkPoint, equal)
+	}
+}
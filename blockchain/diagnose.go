@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"slices"
+	"time"
+)
+
+// A kind of corruption reported by Diagnose.
+type Problem string
+
+// The problems Diagnose can report for a block.
+const (
+	ProblemUnreadable     Problem = "unreadable"            // the block store failed to return the block
+	ProblemDuplicateTx    Problem = "duplicate transaction" // the block records a transaction twice, or one already in an earlier block
+	ProblemMerkleMismatch Problem = "merkle root mismatch"  // the Merkle root doesn't match the transactions
+	ProblemHashMismatch   Problem = "hash mismatch"         // the hash doesn't match the block's contents
+	ProblemBadProofOfWork Problem = "bad proof of work"     // the hash doesn't satisfy the rule the block was mined under
+	ProblemLowWork        Problem = "low work"              // the block was mined below the minimum difficulty
+	ProblemInvalidTx      Problem = "invalid transaction"   // a transaction breaks the rules of what a block may record
+	ProblemBadSignature   Problem = "bad signature"         // a signature doesn't verify, or is missing from a key address's transaction
+	ProblemBrokenLink     Problem = "broken link"           // the previous hash or the height doesn't follow the previous block
+	ProblemTimestamp      Problem = "timestamp anomaly"     // the timestamp is in the future or earlier than the previous block's
+	ProblemAllocations    Problem = "invalid allocations"   // the genesis block's allocations break the rules of CreateBlockchainWithAllocations
+)
+
+// The problems found in one corrupted block, as returned by Diagnose.
+type BlockDiagnostic struct {
+	Index    int       // the position of the block in the chain
+	Hash     string    // the short form of the block's stored hash
+	Problems []Problem // every problem found in the block, in the order Validate checks them
+}
+
+// This method is a forensic counterpart to Validate: instead of stopping at the first failure,
+// it checks every block independently and reports all corrupted blocks, in chain order, with
+// every problem found in each. Blocks without problems are not listed, so a valid chain
+// yields an empty result. Each block is checked against the stored block before it, so a
+// single tampered block also shows up as a broken link in its successor.
+func (b *Blockchain) Diagnose() []BlockDiagnostic {
+	b.rlock()
+	defer b.mu.RUnlock()
+	var diagnostics []BlockDiagnostic
+	latest := b.clock().Add(b.maxClockSkew)
+	var previousBlock Block
+	readable := false
+	seen := make(map[string]int)
+	for i := 0; i < b.store.Len(); i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			diagnostics = append(diagnostics, BlockDiagnostic{Index: i, Hash: "-", Problems: []Problem{ProblemUnreadable}})
+			readable = false
+			continue
+		}
+		var problems []Problem
+		if i == 0 {
+			problems = b.diagnoseGenesis(block, latest)
+		} else {
+			problems = b.diagnoseBlock(block)
+			if readable {
+				problems = append(problems, diagnoseLink(previousBlock, block, latest)...)
+			} else if block.timestamp.After(latest) {
+				problems = append(problems, ProblemTimestamp)
+			}
+			if recordIDs(seen, i, block) != nil && !slices.Contains(problems, ProblemDuplicateTx) {
+				problems = append(problems, ProblemDuplicateTx)
+			}
+		}
+		if len(problems) > 0 {
+			diagnostics = append(diagnostics, BlockDiagnostic{Index: i, Hash: shortHash(block.hash), Problems: problems})
+		}
+		previousBlock = block
+		readable = true
+	}
+	return diagnostics
+}
+
+// This method returns the problems of the genesis block. The caller must hold the lock.
+func (b *Blockchain) diagnoseGenesis(genesis Block, latest time.Time) []Problem {
+	var problems []Problem
+	if genesis.hash == "" || genesis.hash != genesis.calculateHash(b.hashFunc()) {
+		problems = append(problems, ProblemHashMismatch)
+	}
+	if genesis.height != 0 || genesis.hash != b.genesisBlock.hash {
+		problems = append(problems, ProblemBrokenLink)
+	}
+	if checkAllocations(genesis.data) != nil {
+		problems = append(problems, ProblemAllocations)
+	}
+	if genesis.timestamp.After(latest) {
+		problems = append(problems, ProblemTimestamp)
+	}
+	return problems
+}
+
+// This method returns the problems of a block that don't depend on other blocks, as checked
+// by checkBlock. The caller must hold the lock.
+func (b *Blockchain) diagnoseBlock(block Block) []Problem {
+	var problems []Problem
+	for _, c := range blockChecks {
+		if c.check(b, block) != nil {
+			problems = append(problems, c.problem)
+		}
+	}
+	return problems
+}
+
+// This function returns the problems in how a block links to its predecessor, as checked by
+// checkLink.
+func diagnoseLink(previousBlock, block Block, latest time.Time) []Problem {
+	var problems []Problem
+	if block.previousHash != previousBlock.hash || block.height != previousBlock.height+1 {
+		problems = append(problems, ProblemBrokenLink)
+	}
+	if block.timestamp.After(latest) || block.timestamp.Before(previousBlock.timestamp) {
+		problems = append(problems, ProblemTimestamp)
+	}
+	return problems
+}
@@ -0,0 +1,54 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMiningPaysRewardPlusFees(t *testing.T) {
+	b := CreateBlockchainWithAllocations(1, map[string]float64{"alice": 100})
+	b.SetMinerAddress("miner")
+	b.SetMiningReward(10)
+	mineTransactions(t, &b,
+		Transaction{From: "alice", To: "bob", Amount: 20, Fee: 0.5},
+		Transaction{From: "alice", To: "carol", Amount: 5, Fee: 0.25},
+	)
+	for account, want := range map[string]float64{"alice": 74.25, "bob": 20, "carol": 5, "miner": 10.75} {
+		if got := b.BalanceOf(account); got != want {
+			t.Errorf("BalanceOf(%q) = %v, want %v", account, got, want)
+		}
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiagnoseReportsNegativeFee(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1, Timestamp: time.Unix(1, 0)})
+	tamper(t, &b, 1, func(block *Block) {
+		block.transactions[0].Fee = -5
+	})
+	if err := b.Validate(); !errors.Is(err, ErrChainTampered) {
+		t.Fatalf("Validate() = %v, want ErrChainTampered", err)
+	}
+	diagnostics := b.Diagnose()
+	if len(diagnostics) != 1 || len(diagnostics[0].Problems) != 1 || diagnostics[0].Problems[0] != ProblemInvalidTx {
+		t.Fatalf("Diagnose() = %v, want only an invalid transaction in block 1", diagnostics)
+	}
+}
+
+func TestDiagnoseMatchesValidate(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	if diagnostics := b.Diagnose(); len(diagnostics) != 0 {
+		t.Fatalf("Diagnose() = %v on a valid chain", diagnostics)
+	}
+	b.store.(*MemoryStore).blocks[1].transactions[0].Amount = 1000
+	diagnostics := b.Diagnose()
+	if b.Validate() == nil || len(diagnostics) != 1 || diagnostics[0].Problems[0] != ProblemMerkleMismatch {
+		t.Fatalf("Diagnose() = %v, want a Merkle root mismatch in block 1", diagnostics)
+	}
+}
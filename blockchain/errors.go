@@ -0,0 +1,21 @@
+package blockchain
+
+import "errors"
+
+// The sentinel errors of the package. Errors returned by the blockchain wrap them where they
+// apply, so that callers can branch on the kind of error with errors.Is, for example to
+// answer 404 Not Found for ErrBlockNotFound and 400 Bad Request for ErrInvalidTransaction.
+var (
+	// A block was looked up by an index or hash that is not on the chain.
+	ErrBlockNotFound = errors.New("block not found")
+	// A transaction was rejected because it is malformed, badly signed, or refused by strict mode.
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	// A transaction was rejected because its sender can't cover it (see Wallet.Send).
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// A transaction was rejected because it is already queued or on the chain.
+	ErrDuplicateTransaction = errors.New("duplicate transaction")
+	// Validation found a block that fails a check, so the chain has been tampered with or is corrupt.
+	ErrChainTampered = errors.New("chain tampered with")
+	// Mining was aborted because its context was cancelled or its deadline passed.
+	ErrMiningCancelled = errors.New("mining aborted")
+)
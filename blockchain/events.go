@@ -0,0 +1,83 @@
+package blockchain
+
+// This method registers a callback that is invoked synchronously with the newly appended
+// block after each successful mine. Multiple callbacks may be registered; they are called in
+// registration order. Callbacks run after the chain's lock has been released, so they may
+// safely call back into the blockchain. A panicking callback is recovered and does not
+// prevent the remaining callbacks from running.
+func (b *Blockchain) OnBlockMined(fn func(Block)) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.blockMined = append(b.blockMined, fn)
+}
+
+// This method invokes the block-mined callbacks. It must be called without holding the lock.
+func (b *Blockchain) notifyBlockMined(block Block) {
+	b.rlock()
+	callbacks := make([]func(Block), len(b.blockMined))
+	copy(callbacks, b.blockMined)
+	b.mu.RUnlock()
+	for _, fn := range callbacks {
+		callSafely(func() { fn(block) })
+	}
+}
+
+// This method registers a callback that is invoked for every transaction that is refused on
+// submission, through AddTransaction and its variants, QueueTransaction, Wallet.Send or the
+// HTTP handler, with the transaction's parties and amount and the reason it was refused: an
+// invalid input or signature (ErrInvalidTransaction), a duplicate (ErrDuplicateTransaction)
+// or missing funds (ErrInsufficientFunds). This lets operators audit rejected activity in one
+// place. Failures to mine an accepted transaction are not rejections. Multiple callbacks may
+// be registered, and they are called like the OnBlockMined callbacks: in registration order,
+// after the lock has been released, and with panics recovered.
+func (b *Blockchain) OnTransactionRejected(fn func(from, to string, amount float64, reason error)) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.txRejected = append(b.txRejected, fn)
+}
+
+// This method invokes the transaction-rejected callbacks. It must be called without holding
+// the lock.
+func (b *Blockchain) notifyTransactionRejected(tx Transaction, reason error) {
+	b.rlock()
+	callbacks := make([]func(from, to string, amount float64, reason error), len(b.txRejected))
+	copy(callbacks, b.txRejected)
+	b.mu.RUnlock()
+	for _, fn := range callbacks {
+		callSafely(func() { fn(tx.From, tx.To, tx.Amount, reason) })
+	}
+}
+
+// This method sets a structured logger that receives the blockchain's events, so that it can be
+// wired into slog, zap or any other logging library without this package depending on one.
+// The events are:
+//
+//	mine_start      a block is about to be mined; fields height, difficulty and transactions
+//	mine_complete   a block has been mined; fields height, nonce, elapsed (a time.Duration) and hash
+//	mine_failed     mining was aborted; fields height and error
+//	mine_stale      a mined block was discarded, since the chain changed meanwhile; fields height and hash
+//	validate_failed validation failed; fields index (of the first failing block) and error
+//	mempool_expired queued transactions outlived the mempool TTL; field dropped (their number)
+//
+// The logger is called synchronously while the blockchain is locked, so it must not call back
+// into the blockchain. Passing nil removes the logger; without one, no events are emitted.
+func (b *Blockchain) SetLogger(fn func(event string, fields map[string]interface{})) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.logger = fn
+}
+
+// This method emits an event to the logger, if one is set. The caller must hold the lock.
+func (b *Blockchain) log(event string, fields map[string]interface{}) {
+	if b.logger != nil {
+		callSafely(func() { b.logger(event, fields) })
+	}
+}
+
+// This function calls fn, recovering from any panic it raises.
+func callSafely(fn func()) {
+	defer func() {
+		_ = recover()
+	}()
+	fn()
+}
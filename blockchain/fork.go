@@ -0,0 +1,272 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// This method submits a block mined elsewhere, for example by a peer. The block must extend a
+// known block: if its parent is the tip of the chain it is appended, otherwise it is tracked
+// as part of a side branch. When a side branch comes to represent more total work than the
+// main chain (see TotalWork), the fork-choice rule of ReplaceChain, the chain is reorganized
+// to adopt it, and the blocks it displaces are kept as a side branch in turn.
+// The block is checked like in Validate, and must satisfy the current difficulty (or target
+// threshold).
+// An error is returned for invalid blocks, for blocks that are already known, for orphan
+// blocks whose parent is unknown, and for blocks whose branch forks off at or below the
+// finalized height (see Finalize).
+func (b *Blockchain) AddBlock(block Block) error {
+	b.lock()
+	defer b.mu.Unlock()
+	if b.indexOf(block.hash) >= 0 {
+		return fmt.Errorf("block %s is already on the chain", shortHash(block.hash))
+	}
+	if _, ok := b.sideBlocks[block.hash]; ok {
+		return fmt.Errorf("block %s is already known", shortHash(block.hash))
+	}
+	parentIndex := b.indexOf(block.previousHash)
+	parent, onSideBranch := b.sideBlocks[block.previousHash]
+	if parentIndex < 0 && !onSideBranch {
+		return fmt.Errorf("orphan block %s: parent %s is unknown", shortHash(block.hash), shortHash(block.previousHash))
+	}
+	if !onSideBranch {
+		var err error
+		if parent, err = b.store.Get(parentIndex); err != nil {
+			return err
+		}
+	}
+	if err := b.checkCandidate(parent, block); err != nil {
+		return err
+	}
+	height := parent.height + 1
+	if fork := b.forkIndexOf(block.previousHash); fork < b.finalized {
+		return fmt.Errorf("block %d: branch forks off below finalized block %d", height, b.finalized)
+	}
+	if parentIndex == b.store.Len()-1 {
+		if err := b.store.Append(block); err != nil {
+			return err
+		}
+		b.indexBlock(block)
+		return nil
+	}
+	if b.sideBlocks == nil {
+		b.sideBlocks = make(map[string]Block)
+	}
+	b.sideBlocks[block.hash] = block
+	heavier, err := b.outweighsMainChain(block)
+	if err != nil {
+		return err
+	}
+	if heavier {
+		return b.reorganize(block)
+	}
+	return nil
+}
+
+// This method reports whether the side branch ending in tip represents strictly more work
+// than the main-chain blocks it would displace. The caller must hold the lock.
+func (b *Blockchain) outweighsMainChain(tip Block) (bool, error) {
+	branch := new(big.Int)
+	hash := tip.hash
+	forkIndex := -1
+	for forkIndex < 0 {
+		block, ok := b.sideBlocks[hash]
+		if !ok {
+			return false, errors.New("side branch is broken")
+		}
+		branch.Add(branch, block.work())
+		hash = block.previousHash
+		forkIndex = b.indexOf(hash)
+	}
+	main := new(big.Int)
+	for i := forkIndex + 1; i < b.store.Len(); i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return false, err
+		}
+		main.Add(main, block.work())
+	}
+	return branch.Cmp(main) > 0, nil
+}
+
+// This method checks a block mined elsewhere that extends the chain tip, without changing the
+// chain: the block must link to the tip, its hash must recompute correctly, and it must
+// satisfy the current difficulty (or target threshold). It is checked like in Validate
+// otherwise. AddBlock performs the same checks against the block's parent.
+func (b *Blockchain) ValidateCandidateBlock(block Block) error {
+	// The exclusive lock is taken because the check may build the index of transaction IDs.
+	b.lock()
+	defer b.mu.Unlock()
+	tip, err := b.tip()
+	if err != nil {
+		return err
+	}
+	return b.checkCandidate(tip, block)
+}
+
+// This method appends a block mined by a separate process, for example a dedicated mining
+// node, without mining it again. The block must link to the tip of the chain, its hash must
+// recompute correctly, and its proof of work must satisfy the current difficulty (or target
+// threshold); it is checked like in ValidateCandidateBlock, and an error is returned without
+// changing the chain if it fails. Unlike AddBlock, blocks that don't extend the tip are
+// rejected rather than kept as a side branch, and so are blocks that record a transaction
+// already on the chain. Queued transactions that the block records are removed from the
+// mempool, the difficulty is retargeted as after MineBlock (see SetTargetBlockTime), and the
+// OnBlockMined callbacks are invoked with the block.
+func (b *Blockchain) AppendMinedBlock(block Block) error {
+	b.lock()
+	tip, err := b.tip()
+	if err == nil {
+		err = b.checkCandidate(tip, block)
+	}
+	if err == nil {
+		err = b.store.Append(block)
+	}
+	if err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	b.indexBlock(block)
+	recorded := make(map[string]bool, len(block.transactions))
+	for _, tx := range block.transactions {
+		recorded[tx.ID()] = true
+	}
+	b.filterPending(func(tx Transaction, _ time.Time) bool {
+		return !recorded[tx.ID()]
+	})
+	b.retarget(tip)
+	b.mu.Unlock()
+	b.notifyBlockMined(block)
+	return nil
+}
+
+// This method checks a block mined elsewhere as the child of parent, including that it
+// records no transaction already on the branch it extends. The caller must hold the
+// exclusive lock.
+func (b *Blockchain) checkCandidate(parent, block Block) error {
+	height := parent.height + 1
+	if err := b.validateBlock(height, parent, block, b.clock().Add(b.maxClockSkew)); err != nil {
+		return err
+	}
+	if err := b.checkNotOnBranch(parent, block); err != nil {
+		return fmt.Errorf("block %d: %w", height, err)
+	}
+	if !b.currentProofRule()(block.hash) {
+		return fmt.Errorf("block %d: proof of work does not satisfy the current difficulty", height)
+	}
+	return nil
+}
+
+// This method returns the index of the block with the given hash on the main chain, or -1.
+// The chain is searched from the tip, where new blocks usually attach. The caller must hold
+// the lock.
+func (b *Blockchain) indexOf(hash string) int {
+	for i := b.store.Len() - 1; i >= 0; i-- {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return -1
+		}
+		if block.hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// This method returns the index of the main-chain block that the known block with the given
+// hash descends from: the index of the block itself if it is on the main chain, or the point
+// where its side branch forks off. The caller must hold the lock.
+func (b *Blockchain) forkIndexOf(hash string) int {
+	index := b.indexOf(hash)
+	for index < 0 {
+		block, ok := b.sideBlocks[hash]
+		if !ok {
+			return -1
+		}
+		hash = block.previousHash
+		index = b.indexOf(hash)
+	}
+	return index
+}
+
+// This method makes the side branch ending in tip the main chain. The caller must hold the lock.
+func (b *Blockchain) reorganize(tip Block) error {
+	var branch []Block
+	hash := tip.hash
+	forkIndex := -1
+	for forkIndex < 0 {
+		block, ok := b.sideBlocks[hash]
+		if !ok {
+			return errors.New("reorganize: side branch is broken")
+		}
+		branch = append([]Block{block}, branch...)
+		hash = block.previousHash
+		forkIndex = b.indexOf(hash)
+	}
+	chain, err := b.collect()
+	if err != nil {
+		return err
+	}
+	displaced := chain[forkIndex+1:]
+	if err := b.replaceBlocks(append(chain[:forkIndex+1:forkIndex+1], branch...)); err != nil {
+		return err
+	}
+	for _, block := range branch {
+		delete(b.sideBlocks, block.hash)
+	}
+	for _, block := range displaced {
+		b.sideBlocks[block.hash] = block
+	}
+	return nil
+}
+
+// This method returns the proof-of-work rule new blocks must currently satisfy. The caller
+// must hold the lock.
+func (b *Blockchain) currentProofRule() func(hash string) bool {
+	return Block{difficulty: b.difficulty, target: b.target}.proofRule()
+}
+
+// This method returns the stored blocks that are not part of the canonical chain, that is,
+// not reachable from the tip by following previousHash links back towards the genesis block.
+// These are the blocks of side branches kept by AddBlock, including those displaced by a
+// reorganization, and any block on the main chain whose link to its successor is broken,
+// which Validate would also report. Blocks on the main chain come first, in chain order,
+// followed by side-branch blocks ordered by height and hash. A valid chain without side
+// branches has no orphan blocks.
+func (b *Blockchain) OrphanBlocks() []Block {
+	b.rlock()
+	defer b.mu.RUnlock()
+	chain, _ := b.collect()
+	byHash := make(map[string]Block, len(chain))
+	for _, block := range chain {
+		byHash[block.hash] = block
+	}
+	reachable := make(map[string]bool, len(chain))
+	if len(chain) > 0 {
+		block, ok := chain[len(chain)-1], true
+		for ok && !reachable[block.hash] {
+			reachable[block.hash] = true
+			block, ok = byHash[block.previousHash]
+		}
+	}
+	var orphans []Block
+	for _, block := range chain {
+		if !reachable[block.hash] {
+			orphans = append(orphans, block)
+		}
+	}
+	side := make([]Block, 0, len(b.sideBlocks))
+	for _, block := range b.sideBlocks {
+		side = append(side, block)
+	}
+	sort.Slice(side, func(i, j int) bool {
+		if side[i].height != side[j].height {
+			return side[i].height < side[j].height
+		}
+		return side[i].hash < side[j].hash
+	})
+	return append(orphans, side...)
+}
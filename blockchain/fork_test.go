@@ -0,0 +1,184 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// This function mines a block extending the tip of b on a clone of b, applies edit to it
+// like tamper does, and returns it without appending it to b, as a peer would send it.
+func peerBlock(t *testing.T, b *Blockchain, edit func(block *Block)) Block {
+	t.Helper()
+	peer := b.Clone()
+	mineTransactions(t, &peer, Transaction{From: "alice", To: "bob", Amount: 1, Fee: 0.5})
+	tip := peer.store.Len() - 1
+	tamper(t, &peer, tip, edit)
+	return peer.store.(*MemoryStore).blocks[tip]
+}
+
+func TestAddBlockChecksTransactions(t *testing.T) {
+	coinbase := func(amount float64) Transaction {
+		return Transaction{From: CoinbaseAddress, To: "mallory", Amount: amount}
+	}
+	tests := []struct {
+		name string
+		edit func(block *Block)
+	}{
+		{"negative amount", func(block *Block) {
+			block.transactions[0].Amount = -500
+		}},
+		{"empty recipient", func(block *Block) {
+			block.transactions[0].To = ""
+		}},
+		{"two coinbases", func(block *Block) {
+			block.transactions = append(block.transactions, coinbase(1e6), coinbase(2e6))
+		}},
+		{"coinbase first", func(block *Block) {
+			block.transactions = append([]Transaction{coinbase(1)}, block.transactions...)
+		}},
+		{"coinbase above reward plus fees", func(block *Block) {
+			block.transactions = append(block.transactions, coinbase(10.75))
+		}},
+		{"negative coinbase", func(block *Block) {
+			block.transactions = append(block.transactions, coinbase(-1))
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := CreateBlockchain(1)
+			b.SetMiningReward(10)
+			block := peerBlock(t, &b, test.edit)
+			if err := b.AddBlock(block); !errors.Is(err, ErrInvalidTransaction) {
+				t.Fatalf("AddBlock() = %v, want ErrInvalidTransaction", err)
+			}
+			b.store.Append(block)
+			if err := b.Validate(); !errors.Is(err, ErrInvalidTransaction) {
+				t.Fatalf("Validate() = %v, want ErrInvalidTransaction", err)
+			}
+		})
+	}
+}
+
+func TestAddBlockAcceptsRewardPlusFees(t *testing.T) {
+	b := CreateBlockchain(1)
+	b.SetMiningReward(10)
+	block := peerBlock(t, &b, func(block *Block) {
+		block.transactions = append(block.transactions, Transaction{From: CoinbaseAddress, To: "miner", Amount: 10.5})
+	})
+	if err := b.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.BalanceOf("miner"); got != 10.5 {
+		t.Fatalf("BalanceOf(miner) = %v, want 10.5", got)
+	}
+}
+
+func TestAppendMinedBlockRetargets(t *testing.T) {
+	b := CreateBlockchain(1)
+	b.SetTargetBlockTime(time.Hour)
+	miner := b.Clone()
+	block := mineTransactions(t, &miner, Transaction{From: "alice", To: "bob", Amount: 1})
+	if err := b.AppendMinedBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Difficulty(); got != 2 {
+		t.Fatalf("Difficulty() = %d after a fast block, want 2", got)
+	}
+	if err := b.AppendMinedBlock(block); err == nil {
+		t.Fatal("AppendMinedBlock() accepted the same block twice")
+	}
+}
+
+func TestAddBlockReorganizes(t *testing.T) {
+	b := CreateBlockchain(1)
+	peer := b.Clone()
+	ours := mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	first := mineTransactions(t, &peer, Transaction{From: "alice", To: "carol", Amount: 1})
+	second := mineTransactions(t, &peer, Transaction{From: "carol", To: "dave", Amount: 1})
+
+	if err := b.AddBlock(second); err == nil {
+		t.Fatal("AddBlock() accepted an orphan block")
+	}
+	if err := b.AddBlock(first); err != nil {
+		t.Fatal(err)
+	}
+	if b.LatestBlock().Hash() != ours.Hash() {
+		t.Fatal("AddBlock() reorganized to a side branch that is not heavier")
+	}
+	if err := b.AddBlock(second); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 3 || b.LatestBlock().Hash() != second.Hash() {
+		t.Fatalf("chain of %d blocks ends in %s after the reorganization, want 3 ending in %s", b.Len(), b.LatestBlock().Hash(), second.Hash())
+	}
+	if orphans := b.OrphanBlocks(); len(orphans) != 1 || orphans[0].Hash() != ours.Hash() {
+		t.Fatalf("OrphanBlocks() = %v, want the displaced block", orphans)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.BalanceOf("bob"); got != 0 {
+		t.Fatalf("BalanceOf(bob) = %v, want 0 once the block paying bob is displaced", got)
+	}
+}
+
+func TestOrphanBlocks(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	if orphans := b.OrphanBlocks(); len(orphans) != 0 {
+		t.Fatalf("OrphanBlocks() of a valid chain = %v, want none", orphans)
+	}
+	side := peerBlock(t, &b, func(block *Block) {})
+
+	// A block of another chain injected into storage, which no block links to.
+	other := CreateBlockchain(1)
+	injected := mineTransactions(t, &other, Transaction{From: "mallory", To: "eve", Amount: 1})
+	store := b.store.(*MemoryStore)
+	store.blocks = append(store.blocks[:2:2], injected, store.blocks[2])
+	if orphans := b.OrphanBlocks(); len(orphans) != 1 || orphans[0].Hash() != injected.Hash() {
+		t.Fatalf("OrphanBlocks() = %v, want the injected block", orphans)
+	}
+	if b.Validate() == nil {
+		t.Fatal("Validate() accepted a chain with an injected block")
+	}
+
+	b.sideBlocks = map[string]Block{side.hash: side}
+	if orphans := b.OrphanBlocks(); len(orphans) != 2 || orphans[1].Hash() != side.Hash() {
+		t.Fatalf("OrphanBlocks() = %v, want the injected block followed by the side block", orphans)
+	}
+}
+
+func TestAddBlockPrefersWorkOverLength(t *testing.T) {
+	b := CreateBlockchain(1)
+	heavy := b.Clone()
+	light := b.Clone()
+	for i := 0; i < 2; i++ {
+		mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1, Nonce: uint64(i)})
+	}
+	if err := heavy.SetDifficulty(3); err != nil {
+		t.Fatal(err)
+	}
+	heavyBlock := mineTransactions(t, &heavy, Transaction{From: "alice", To: "carol", Amount: 1})
+	if err := b.AddBlock(heavyBlock); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 2 || b.LatestBlock().Hash() != heavyBlock.Hash() {
+		t.Fatalf("chain of %d blocks after AddBlock, want the 2 blocks of the heavier branch", b.Len())
+	}
+
+	// A longer but lighter branch is kept on the side.
+	for i := 0; i < 3; i++ {
+		block := mineTransactions(t, &light, Transaction{From: "alice", To: "dave", Amount: 1, Nonce: uint64(i)})
+		if err := b.AddBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.LatestBlock().Hash() != heavyBlock.Hash() {
+		t.Fatal("AddBlock() reorganized to a longer but lighter side branch")
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
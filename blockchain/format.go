@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// This method renders the blockchain as human-readable text, one line per block, showing the
+// index, the short hash (its first 8 hex characters), the previous short hash, the nonce, the
+// timestamp in UTC with nanoseconds, and a summary of the block's transactions. The nonce and
+// timestamp are printed in full; use AuditString to see the full hashes as well. It has a
+// value receiver so that both fmt.Println(chain) and fmt.Println(&chain) use it.
+func (b Blockchain) String() string {
+	return b.render(shortHash)
+}
+
+// This method renders the blockchain like String, but with the full hashes, so that an
+// auditor can check them against the JSON form of the chain.
+func (b Blockchain) AuditString() string {
+	return b.render(orDash)
+}
+
+// This method implements String and AuditString, showing every hash as formatted by hash.
+func (b Blockchain) render(hash func(string) string) string {
+	if b.chainState == nil {
+		return "(empty blockchain)\n"
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var sb strings.Builder
+	for i, block := range b.blocks(0) {
+		label := fmt.Sprintf("Block %d", i)
+		if i == 0 {
+			label += " (genesis)"
+		}
+		fmt.Fprintf(&sb, "%s hash=%s prev=%s nonce=%d time=%s %s\n",
+			label,
+			hash(block.hash),
+			hash(block.previousHash),
+			block.pow,
+			block.timestamp.UTC().Format(time.RFC3339Nano),
+			block.summary(),
+		)
+	}
+	return sb.String()
+}
+
+// This method returns a one-line summary of the block's transactions.
+func (b Block) summary() string {
+	if len(b.transactions) == 0 {
+		return "(no transactions)"
+	}
+	parts := make([]string, len(b.transactions))
+	for i, tx := range b.transactions {
+		parts[i] = fmt.Sprintf("%s -> %s: %v", tx.From, tx.To, tx.Amount)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// This function returns the hash, or "-" if it is empty.
+func orDash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	return hash
+}
+
+// This function shortens a hash to its first 8 hex characters for display.
+func shortHash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
@@ -0,0 +1,25 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	b := CreateBlockchain(1)
+	block := mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("String() has %d lines, want 2:\n%s", len(lines), b.String())
+	}
+	if !strings.HasPrefix(lines[0], "Block 0 (genesis) ") || !strings.Contains(lines[0], " prev=- ") {
+		t.Errorf("String() renders the genesis block as %q", lines[0])
+	}
+	want := "Block 1 hash=" + block.Hash()[:8] + " prev=" + block.PreviousHash()[:8] + " "
+	if !strings.HasPrefix(lines[1], want) || !strings.Contains(lines[1], "alice -> bob: 1") {
+		t.Errorf("String() renders block 1 as %q, want it to start with %q", lines[1], want)
+	}
+	if audit := b.AuditString(); !strings.Contains(audit, "hash="+block.Hash()+" prev="+block.PreviousHash()+" ") {
+		t.Errorf("AuditString() = %q, want the full hashes of block 1", audit)
+	}
+}
@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+func init() {
+	// Block data is a map of arbitrary values, so the concrete types that can appear inside
+	// it must be registered with gob.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// This method implements gob.GobEncoder.
+func (b Block) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(b.wire()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// This method implements gob.GobDecoder.
+func (b *Block) GobDecode(content []byte) error {
+	var decoded blockWire
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&decoded); err != nil {
+		return err
+	}
+	*b = decoded.block()
+	return nil
+}
+
+// The gob form of a blockchain. Unlike blockchainWire, it holds the serializable form of the
+// blocks rather than the blocks themselves, so that their type is sent once for the whole chain
+// instead of once per block by Block.GobEncode.
+type blockchainGob struct {
+	Difficulty    int
+	HashAlgorithm string
+	Finalized     int
+	MiningReward  float64
+	MinDifficulty int
+	Blocks        []blockWire
+}
+
+// This function returns the gob form of the serializable blockchain.
+func gobOf(saved blockchainWire) blockchainGob {
+	blocks := make([]blockWire, len(saved.Blocks))
+	for i, block := range saved.Blocks {
+		blocks[i] = block.wire()
+	}
+	return blockchainGob{
+		Difficulty:    saved.Difficulty,
+		HashAlgorithm: saved.HashAlgorithm,
+		Finalized:     saved.Finalized,
+		MiningReward:  saved.MiningReward,
+		MinDifficulty: saved.MinDifficulty,
+		Blocks:        blocks,
+	}
+}
+
+// This method returns the serializable blockchain of the gob form.
+func (g blockchainGob) wire() blockchainWire {
+	blocks := make([]Block, len(g.Blocks))
+	for i, block := range g.Blocks {
+		blocks[i] = block.block()
+	}
+	return blockchainWire{
+		Difficulty:    g.Difficulty,
+		HashAlgorithm: g.HashAlgorithm,
+		Finalized:     g.Finalized,
+		MiningReward:  g.MiningReward,
+		MinDifficulty: g.MinDifficulty,
+		Blocks:        blocks,
+	}
+}
+
+// This method writes the blockchain, including the genesis block, the difficulty, the hash
+// algorithm, the finalized height, the mining reward and the minimum difficulty, to w using
+// encoding/gob. This is more compact than JSON for sending whole chains between nodes.
+func (b *Blockchain) EncodeGob(w io.Writer) error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	saved, err := b.wire()
+	if err != nil {
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	if err := gob.NewEncoder(w).Encode(gobOf(saved)); err != nil {
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	return nil
+}
+
+// This function reads a blockchain previously written by EncodeGob.
+// The decoded chain is validated, and an error is returned if it has been tampered with.
+func DecodeGob(r io.Reader) (Blockchain, error) {
+	var saved blockchainGob
+	if err := gob.NewDecoder(r).Decode(&saved); err != nil {
+		return Blockchain{}, fmt.Errorf("decode blockchain: %w", err)
+	}
+	return saved.wire().restore("", 0)
+}
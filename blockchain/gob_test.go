@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestGobRoundTrip(t *testing.T) {
+	b := CreateBlockchainWithGenesis(1, map[string]interface{}{"nested": map[string]interface{}{"list": []interface{}{"a", 1.5}}}, GenesisEpoch())
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1.25})
+	if err := b.Finalize(1); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := b.EncodeGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeGob(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.IsValid() || decoded.ChainHash() != b.ChainHash() || decoded.FinalizedHeight() != 1 {
+		t.Fatalf("DecodeGob() returned %v, want a valid copy of %v", decoded, b)
+	}
+	for i, block := range decoded.Blocks() {
+		if got := block.calculateHash(decoded.hashFunc()); got != block.Hash() {
+			t.Errorf("block %d: recomputed hash %s, want %s", i, got, block.Hash())
+		}
+	}
+}
+
+func TestGobIsSmallerThanJSON(t *testing.T) {
+	chain := longChain(t, 100, func(i int) (string, string) {
+		return fmt.Sprintf("account%d", i%10), fmt.Sprintf("account%d", (i+1)%10)
+	})
+	var buf bytes.Buffer
+	if err := chain.EncodeGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(mustWire(t, chain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(data) {
+		t.Fatalf("gob encoding has %d bytes, want fewer than the %d bytes of JSON", buf.Len(), len(data))
+	}
+}
+
+// This benchmark reports the size of a 1000-block chain encoded with gob and as JSON.
+func BenchmarkEncodingSize(b *testing.B) {
+	chain := longChain(b, 1000, func(i int) (string, string) {
+		return fmt.Sprintf("account%d", i%10), fmt.Sprintf("account%d", (i+1)%10)
+	})
+	b.Run("gob", func(b *testing.B) {
+		var buf bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			if err := chain.EncodeGob(&buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(buf.Len()), "bytes")
+	})
+	b.Run("json", func(b *testing.B) {
+		var data []byte
+		for i := 0; i < b.N; i++ {
+			saved, err := chain.wire()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if data, err = json.Marshal(saved); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(len(data)), "bytes")
+	})
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+)
+
+// The registry of named hash algorithms a chain may use for its block hashes. The name of a
+// chain's algorithm is recorded when the chain is saved, so that a loaded chain is validated
+// with the same algorithm.
+var (
+	hashAlgorithmsMu sync.RWMutex
+	hashAlgorithms   = map[string]func() hash.Hash{
+		"sha256":     sha256.New,
+		"sha224":     sha256.New224,
+		"sha384":     sha512.New384,
+		"sha512":     sha512.New,
+		"sha512/256": sha512.New512_256,
+	}
+)
+
+// The name of the default hash algorithm.
+const DefaultHashAlgorithm = "sha256"
+
+// This function registers a hash algorithm under a name, so that chains using it can be saved
+// and loaded, e.g. to experiment with SHA-3 or BLAKE2. The SHA-2 family is registered by
+// default. Register algorithms before loading chains that use them.
+func RegisterHashAlgorithm(name string, h func() hash.Hash) {
+	hashAlgorithmsMu.Lock()
+	defer hashAlgorithmsMu.Unlock()
+	hashAlgorithms[name] = h
+}
+
+// This function looks up a registered hash algorithm by name. The empty name means
+// DefaultHashAlgorithm.
+func lookupHashAlgorithm(name string) (func() hash.Hash, error) {
+	if name == "" {
+		name = DefaultHashAlgorithm
+	}
+	hashAlgorithmsMu.RLock()
+	defer hashAlgorithmsMu.RUnlock()
+	h, ok := hashAlgorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("hash algorithm %q is not registered", name)
+	}
+	return h, nil
+}
+
+// This function finds the registered name of a hash algorithm, by comparing its digest of a
+// probe message with those of the registered algorithms.
+func hashAlgorithmName(h func() hash.Hash) (string, error) {
+	probe := []byte("blockchain hash algorithm probe")
+	want := digest(h, probe)
+	hashAlgorithmsMu.RLock()
+	defer hashAlgorithmsMu.RUnlock()
+	for name, candidate := range hashAlgorithms {
+		if bytes.Equal(digest(candidate, probe), want) {
+			return name, nil
+		}
+	}
+	return "", errors.New("hash algorithm is not registered; call RegisterHashAlgorithm first")
+}
+
+// This function hashes the data with a fresh instance of the hash algorithm.
+func digest(h func() hash.Hash, data []byte) []byte {
+	hasher := h()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+// This function creates a new blockchain like CreateBlockchain, but computing block hashes
+// with the given hash algorithm, such as sha512.New, instead of SHA-256. The algorithm is used
+// consistently for mining and validation. To save and load the chain, the algorithm must be
+// registered with RegisterHashAlgorithm, unless it is one of the SHA-2 family.
+// Transaction hashes and Merkle roots always use SHA-256.
+func CreateBlockchainWithHasher(difficulty int, h func() hash.Hash) Blockchain {
+	return createBlockchain(difficulty, nil, time.Now(), h)
+}
+
+// This method returns the highest difficulty of the chain's hash algorithm.
+func (b *Blockchain) maxDifficulty() int {
+	return maxDifficulty(b.hashFunc())
+}
+
+// This method returns the chain's hash algorithm.
+func (b *Blockchain) hashFunc() func() hash.Hash {
+	if b.hasher == nil {
+		return sha256.New
+	}
+	return b.hasher
+}
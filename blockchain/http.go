@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// The largest transaction body POST /transactions accepts. Signed transactions carry a public
+// key and a signature, which take a few hundred bytes, so this leaves ample room.
+const maxTransactionBody = 8 << 10
+
+// The body of an error response sent by the handler returned by HTTPHandler.
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// The body of a response to GET /balance/{account}.
+type balanceResponse struct {
+	Account string  `json:"account"`
+	Balance float64 `json:"balance"`
+}
+
+// This method returns an http.Handler exposing the blockchain as a JSON service:
+//
+//	GET  /blocks            the whole chain, in the format written by SaveToFile, with the chain hash as ETag
+//	GET  /blocks/{index}    a single block, where 0 is the genesis block
+//	GET  /balance/{account} the balance of an account, as computed by BalanceOf
+//	POST /transactions      a transaction with from, to and amount, which is mined into a new block
+//
+// A successful POST answers 201 Created with the mined block, and a body larger than 8 KiB is
+// answered 413 Request Entity Too Large. Errors are answered with a JSON object holding an
+// "error" message.
+func (b *Blockchain) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blocks", b.serveBlocks)
+	mux.HandleFunc("GET /blocks/{index}", b.serveBlock)
+	mux.HandleFunc("GET /balance/{account}", b.serveBalance)
+	mux.HandleFunc("POST /transactions", b.serveTransaction)
+	return mux
+}
+
+// This method answers GET /blocks. The chain hash (see ChainHash) is sent as the ETag, so that a
+// client that already has the current chain is answered 304 Not Modified.
+func (b *Blockchain) serveBlocks(w http.ResponseWriter, r *http.Request) {
+	b.rlock()
+	etag := strconv.Quote(b.chainHash())
+	if r.Header.Get("If-None-Match") == etag {
+		b.mu.RUnlock()
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	saved, err := b.wire()
+	b.mu.RUnlock()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// This method answers GET /blocks/{index}.
+func (b *Blockchain) serveBlock(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	block, err := b.BlockAt(index)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, block)
+}
+
+// This method answers GET /balance/{account}.
+func (b *Blockchain) serveBalance(w http.ResponseWriter, r *http.Request) {
+	account := r.PathValue("account")
+	writeJSON(w, http.StatusOK, balanceResponse{Account: account, Balance: b.BalanceOf(account)})
+}
+
+// This method answers POST /transactions. Invalid and duplicate transactions are rejected
+// with 400 Bad Request before any mining happens; mining itself stops when the client goes away.
+func (b *Blockchain) serveTransaction(w http.ResponseWriter, r *http.Request) {
+	var tx Transaction
+	body := http.MaxBytesReader(w, r.Body, maxTransactionBody)
+	if err := json.NewDecoder(body).Decode(&tx); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	block, err := b.submitTransaction(r.Context(), tx, false)
+	if errors.Is(err, ErrInvalidTransaction) || errors.Is(err, ErrDuplicateTransaction) {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, block)
+}
+
+// This function writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// This function writes err as a JSON error response with the given status code.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, httpError{Error: err.Error()})
+}
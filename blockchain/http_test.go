@@ -0,0 +1,60 @@
+package blockchain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestServeTransaction(t *testing.T) {
+	b := CreateBlockchain(1)
+	handler := b.HTTPHandler()
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"valid", `{"from":"alice","to":"bob","amount":1}`, http.StatusCreated},
+		{"malformed", `{"from":`, http.StatusBadRequest},
+		{"invalid", `{"from":"alice","to":"bob","amount":-1}`, http.StatusBadRequest},
+		{"too large", `{"from":"alice","to":"bob","amount":1,"nonce":1` + strings.Repeat(" ", maxTransactionBody) + `}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(test.body)))
+			if recorder.Code != test.want {
+				t.Fatalf("POST /transactions = %d %s, want %d", recorder.Code, recorder.Body, test.want)
+			}
+		})
+	}
+}
+
+func TestServeBlocksETag(t *testing.T) {
+	b := CreateBlockchain(1)
+	handler := b.HTTPHandler()
+	get := func(etag string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/blocks", nil)
+		if etag != "" {
+			request.Header.Set("If-None-Match", etag)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag != strconv.Quote(b.ChainHash()) {
+		t.Fatalf("GET /blocks = %d with ETag %s, want 200 with the quoted chain hash", first.Code, etag)
+	}
+	if cached := get(etag); cached.Code != http.StatusNotModified || cached.Body.Len() != 0 {
+		t.Fatalf("GET /blocks with the current ETag = %d %s, want 304 without a body", cached.Code, cached.Body)
+	}
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	changed := get(etag)
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == etag {
+		t.Fatalf("GET /blocks with a stale ETag = %d with ETag %s, want 200 with a new ETag", changed.Code, changed.Header().Get("ETag"))
+	}
+}
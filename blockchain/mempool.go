@@ -0,0 +1,219 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The serializable form of the mempool, as written by SaveMempool.
+type mempoolWire struct {
+	Transactions []Transaction `json:"transactions"`
+}
+
+// This method validates the transaction and adds it to the mempool, where it waits until the
+// next call to MineBlock. A transaction without a timestamp is stamped with the blockchain's clock.
+// An error is returned if a transaction with the same ID is already queued or on the chain, or
+// if strict mode rejects the transaction (see SetStrictMode).
+func (b *Blockchain) QueueTransaction(tx Transaction) error {
+	if err := tx.validate(); err != nil {
+		b.notifyTransactionRejected(tx, err)
+		return err
+	}
+	b.lock()
+	if tx.Timestamp.IsZero() {
+		tx.Timestamp = b.clock()
+	}
+	if err := b.checkAdmissible(tx, false); err != nil {
+		b.mu.Unlock()
+		b.notifyTransactionRejected(tx, err)
+		return err
+	}
+	b.enqueue(tx)
+	b.mu.Unlock()
+	return nil
+}
+
+// This method appends the transaction to the mempool and records when it was queued. The
+// caller must hold the exclusive lock.
+func (b *Blockchain) enqueue(tx Transaction) {
+	b.pending = append(b.pending, tx)
+	b.queuedAt = append(b.queuedAt, b.clock())
+}
+
+// This method returns a copy of the transactions waiting in the mempool, in queue order.
+func (b *Blockchain) PendingTransactions() []Transaction {
+	b.rlock()
+	defer b.mu.RUnlock()
+	pending := make([]Transaction, len(b.pending))
+	copy(pending, b.pending)
+	return pending
+}
+
+// This method mines a block holding the queued transactions, in queue order, and removes
+// them from the mempool. At most the limit set by SetMaxTransactionsPerBlock is packed into
+// the block; the remainder stays queued for the next block. Transactions that have waited
+// longer than the TTL set by SetMempoolTTL are discarded first, as by ExpireMempool.
+// An error is returned if the mempool is empty. Queued transactions that have reached the
+// chain in the meantime, for example in a block added with AddBlock, are dropped from the
+// mempool, and an error is returned instead of recording them a second time.
+func (b *Blockchain) MineBlock() (Block, error) {
+	return b.MineBlockContext(context.Background())
+}
+
+// This method is like MineBlock, but aborts mining when the context is done. The queued
+// transactions stay in the mempool in that case.
+func (b *Blockchain) MineBlockContext(ctx context.Context) (Block, error) {
+	var mined map[string]bool
+	block, err := b.mineBlock(ctx, func() ([]Transaction, map[string]interface{}, error) {
+		if expired := b.expirePending(); expired > 0 && len(b.pending) == 0 {
+			return nil, nil, fmt.Errorf("no pending transactions to mine: %d expired", expired)
+		}
+		if len(b.pending) == 0 {
+			return nil, nil, errors.New("no pending transactions to mine")
+		}
+		if err := b.dropRecordedPending(); err != nil {
+			return nil, nil, err
+		}
+		n := len(b.pending)
+		if b.maxBlockTxs > 0 && n > b.maxBlockTxs {
+			n = b.maxBlockTxs
+		}
+		txs := append([]Transaction(nil), b.pending[:n]...)
+		mined = make(map[string]bool, n)
+		for _, tx := range txs {
+			mined[tx.ID()] = true
+		}
+		return txs, nil, nil
+	}, func() {
+		// The mined transactions are removed by ID rather than by position, since the
+		// mempool may have changed while the block was mined without the lock.
+		b.filterPending(func(tx Transaction, _ time.Time) bool { return !mined[tx.ID()] })
+	})
+	if err != nil {
+		return Block{}, err
+	}
+	b.notifyBlockMined(block)
+	return block, nil
+}
+
+// This method limits how many queued transactions MineBlock packs into one block.
+// A limit of 0 (the default) means unlimited.
+func (b *Blockchain) SetMaxTransactionsPerBlock(n int) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.maxBlockTxs = n
+}
+
+// This method removes the queued transactions that are already on the chain from the
+// mempool, and returns an error naming the first of them. The caller must hold the lock.
+func (b *Blockchain) dropRecordedPending() error {
+	var first error
+	b.filterPending(func(tx Transaction, _ time.Time) bool {
+		if err := b.checkNotOnChain(tx.ID()); err != nil {
+			if first == nil {
+				first = err
+			}
+			return false
+		}
+		return true
+	})
+	return first
+}
+
+// This method sets how long a transaction may wait in the mempool, measured from when it was
+// queued with QueueTransaction or LoadMempool. Older transactions are discarded when mining
+// rather than included in a block, so that a stale transaction is not mined hours later at an
+// unexpected time. A TTL of 0 (the default) means queued transactions never expire.
+func (b *Blockchain) SetMempoolTTL(d time.Duration) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.mempoolTTL = d
+}
+
+// This method discards the queued transactions that have waited longer than the TTL set by
+// SetMempoolTTL, and returns how many were dropped. MineBlock calls it before packing a block,
+// and emits a mempool_expired event with the number of dropped transactions (see SetLogger).
+func (b *Blockchain) ExpireMempool() int {
+	b.lock()
+	defer b.mu.Unlock()
+	return b.expirePending()
+}
+
+// This method implements ExpireMempool. The caller must hold the exclusive lock.
+func (b *Blockchain) expirePending() int {
+	if b.mempoolTTL <= 0 {
+		return 0
+	}
+	now := b.clock()
+	expired := b.filterPending(func(_ Transaction, queued time.Time) bool {
+		return now.Sub(queued) <= b.mempoolTTL
+	})
+	if expired > 0 {
+		b.log("mempool_expired", map[string]interface{}{"dropped": expired})
+	}
+	return expired
+}
+
+// This method keeps only the queued transactions for which keep returns true, preserving
+// their order, and returns how many were removed. The caller must hold the exclusive lock.
+func (b *Blockchain) filterPending(keep func(tx Transaction, queued time.Time) bool) int {
+	pending, queuedAt := b.pending[:0:0], b.queuedAt[:0:0]
+	for i, tx := range b.pending {
+		if keep(tx, b.queuedAt[i]) {
+			pending = append(pending, tx)
+			queuedAt = append(queuedAt, b.queuedAt[i])
+		}
+	}
+	removed := len(b.pending) - len(pending)
+	if removed > 0 {
+		b.pending, b.queuedAt = pending, queuedAt
+	}
+	return removed
+}
+
+// This method writes the transactions waiting in the mempool to w as JSON, so that they
+// survive a restart of the node. See LoadMempool.
+func (b *Blockchain) SaveMempool(w io.Writer) error {
+	b.rlock()
+	saved := mempoolWire{Transactions: append([]Transaction{}, b.pending...)}
+	b.mu.RUnlock()
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		return fmt.Errorf("encode mempool: %w", err)
+	}
+	return nil
+}
+
+// This method reads transactions previously written by SaveMempool and queues them after the
+// transactions already waiting in the mempool. Transactions that have reached the chain in the
+// meantime, or that are already queued, are skipped so they are not applied twice. The loaded
+// transactions count as queued now for the TTL set by SetMempoolTTL.
+// An error is returned, and nothing is queued, if the input can't be decoded or holds an
+// invalid transaction, including one that strict mode rejects (see SetStrictMode).
+func (b *Blockchain) LoadMempool(r io.Reader) error {
+	var saved mempoolWire
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return fmt.Errorf("decode mempool: %w", err)
+	}
+	for _, tx := range saved.Transactions {
+		if err := tx.validate(); err != nil {
+			return fmt.Errorf("decode mempool: %w", err)
+		}
+	}
+	b.lock()
+	defer b.mu.Unlock()
+	for _, tx := range saved.Transactions {
+		if err := b.checkStrict(tx); err != nil {
+			return fmt.Errorf("decode mempool: %w", err)
+		}
+	}
+	for _, tx := range saved.Transactions {
+		if b.checkNotRecorded(tx) == nil {
+			b.enqueue(tx)
+		}
+	}
+	return nil
+}
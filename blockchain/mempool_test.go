@@ -0,0 +1,33 @@
+package blockchain
+
+import "testing"
+
+func TestSetMaxTransactionsPerBlock(t *testing.T) {
+	b := CreateBlockchain(1)
+	b.SetMaxTransactionsPerBlock(2)
+	for i := 0; i < 5; i++ {
+		if err := b.QueueTransaction(Transaction{From: "alice", To: "bob", Amount: 1, Nonce: uint64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sizes []int
+	for len(b.PendingTransactions()) > 0 {
+		block, err := b.MineBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(block.Transactions()))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Fatalf("mined blocks of %v transactions, want [2 2 1]", sizes)
+	}
+	var nonce uint64
+	for _, block := range b.Blocks()[1:] {
+		for _, tx := range block.Transactions() {
+			if tx.Nonce != nonce {
+				t.Fatalf("transaction %d mined out of order, want %d", tx.Nonce, nonce)
+			}
+			nonce++
+		}
+	}
+}
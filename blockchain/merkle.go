@@ -0,0 +1,129 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// This method returns the Merkle root of the block's transactions, as stored when the block was created.
+func (b Block) MerkleRoot() string {
+	return b.merkleRoot
+}
+
+// This method returns the Merkle proof that the transaction is included in the block: the
+// hashes of the siblings on the path from the transaction's leaf up to the root, lowest
+// first. The last node of an odd level has an empty sibling. Use VerifyMerkleProof to check
+// the proof against a root. The boolean is false if the transaction is not in the block.
+func (b Block) ProveInclusion(tx Transaction) ([]string, bool) {
+	level := leafHashes(b.Transactions())
+	target := tx.Hash()
+	index := -1
+	for i, leaf := range level {
+		if leaf == target {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, false
+	}
+	proof := []string{}
+	for len(level) > 1 {
+		sibling := ""
+		if index^1 < len(level) {
+			sibling = level[index^1]
+		}
+		proof = append(proof, sibling)
+		level = nextLevel(level)
+		index /= 2
+	}
+	return proof, true
+}
+
+// This function reports whether the proof, as returned by ProveInclusion, links the
+// transaction at the given index among the block's transactions to the Merkle root. The index
+// tells on which side of each sibling the path runs.
+func VerifyMerkleProof(tx Transaction, index int, proof []string, root string) bool {
+	if index < 0 || index >= 1<<len(proof) {
+		return false
+	}
+	current := tx.Hash()
+	for _, sibling := range proof {
+		if index%2 == 1 {
+			current = hashNode(sibling, current)
+		} else {
+			current = hashNode(current, sibling)
+		}
+		index /= 2
+	}
+	return current == root
+}
+
+// This function computes the Merkle root of the given transactions, in order. A block
+// without transactions has an empty root, and a block with one transaction has that
+// transaction's hash as its root.
+// The leaves are the transactions' hashes, and inner nodes are hashed with a prefix that the
+// JSON a transaction hash is computed from can never start with, so that a leaf can't pose as
+// an inner node. The last node of an odd level is paired with an empty sibling instead of a
+// copy of itself, so repeating a transaction always changes the root, unlike in Merkle trees
+// that duplicate the odd node (CVE-2012-2459).
+func merkleRoot(transactions []Transaction) string {
+	level := leafHashes(transactions)
+	if len(level) == 0 {
+		return ""
+	}
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return level[0]
+}
+
+// This function returns the leaf hashes of the transactions.
+func leafHashes(transactions []Transaction) []string {
+	hashes := make([]string, len(transactions))
+	for i, tx := range transactions {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
+
+// This function returns the level above the given one: the hashes of its pairs of nodes, in
+// order, where the last node of an odd level is paired with an empty sibling.
+func nextLevel(level []string) []string {
+	next := make([]string, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		sibling := ""
+		if i+1 < len(level) {
+			sibling = level[i+1]
+		}
+		next = append(next, hashNode(level[i], sibling))
+	}
+	return next
+}
+
+// This function hashes two sibling nodes into their parent. The order of the siblings is part
+// of the hash, so the root also commits to the order of the transactions; the separator
+// keeps an empty sibling on the left apart from one on the right.
+func hashNode(left, right string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte("\x01"+left+"\x00"+right)))
+}
+
+// This method checks that the transactions of the block at the given index are intact by
+// recomputing only their Merkle root and comparing it to the root stored on the block. The
+// block header is not rehashed, which makes this much cheaper than Validate for a light client
+// that only cares about one block's transaction set.
+func (b *Blockchain) VerifyBlockData(index int) error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	if index < 0 || index >= b.store.Len() {
+		return fmt.Errorf("%w: index %d out of range [0, %d]", ErrBlockNotFound, index, b.store.Len()-1)
+	}
+	block, err := b.store.Get(index)
+	if err != nil {
+		return err
+	}
+	if block.merkleRoot != merkleRoot(block.transactions) {
+		return fmt.Errorf("block %d: merkle root mismatch", index)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// This function returns n distinct transactions.
+func testTransactions(n int) []Transaction {
+	transactions := make([]Transaction, n)
+	for i := range transactions {
+		transactions[i] = Transaction{From: "alice", To: "bob", Amount: float64(i + 1), Timestamp: time.Unix(int64(i), 0).UTC()}
+	}
+	return transactions
+}
+
+func TestMerkleRootChangesWhenOddLeafIsRepeated(t *testing.T) {
+	txs := testTransactions(3)
+	if merkleRoot(txs) == merkleRoot(append(txs, txs[2])) {
+		t.Fatal("[a b c] and [a b c c] have the same Merkle root")
+	}
+}
+
+func TestMerkleRootCoversOrder(t *testing.T) {
+	txs := testTransactions(2)
+	if merkleRoot(txs) == merkleRoot([]Transaction{txs[1], txs[0]}) {
+		t.Fatal("swapping two transactions doesn't change the Merkle root")
+	}
+}
+
+func TestSingleTransactionMerkleRoot(t *testing.T) {
+	tx := testTransactions(1)[0]
+	if got := merkleRoot([]Transaction{tx}); got != tx.Hash() {
+		t.Fatalf("the root of a single transaction is %s, want its hash %s", got, tx.Hash())
+	}
+}
+
+func TestProveInclusion(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			txs := testTransactions(n)
+			block := Block{transactions: txs, merkleRoot: merkleRoot(txs)}
+			for i, tx := range txs {
+				proof, ok := block.ProveInclusion(tx)
+				if !ok {
+					t.Fatalf("transaction %d not found", i)
+				}
+				if !VerifyMerkleProof(tx, i, proof, block.MerkleRoot()) {
+					t.Errorf("proof of transaction %d doesn't verify", i)
+				}
+				if VerifyMerkleProof(testTransactions(n + 1)[n], i, proof, block.MerkleRoot()) {
+					t.Errorf("proof of transaction %d verifies another transaction", i)
+				}
+				if n > 1 && VerifyMerkleProof(tx, i^1, proof, block.MerkleRoot()) {
+					t.Errorf("proof of transaction %d verifies at index %d", i, i^1)
+				}
+			}
+			if _, ok := block.ProveInclusion(testTransactions(n + 1)[n]); ok {
+				t.Error("found a transaction that is not in the block")
+			}
+		})
+	}
+}
+
+func TestValidateRejectsDuplicatedCoinbase(t *testing.T) {
+	b := CreateBlockchain(1)
+	b.SetMinerAddress("miner")
+	b.SetMiningReward(10)
+	txs := testTransactions(2)
+	mineTransactions(t, &b, txs...)
+	tip := b.store.Len() - 1
+	tamper(t, &b, tip, func(block *Block) {
+		block.transactions = append(block.transactions, block.transactions[len(block.transactions)-1])
+	})
+	err := b.Validate()
+	if !errors.Is(err, ErrChainTampered) || !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("Validate() = %v, want a duplicate transaction", err)
+	}
+	diagnostics := b.Diagnose()
+	if len(diagnostics) != 1 || diagnostics[0].Index != tip || diagnostics[0].Problems[0] != ProblemDuplicateTx {
+		t.Fatalf("Diagnose() = %v, want a duplicate transaction in block %d", diagnostics, tip)
+	}
+}
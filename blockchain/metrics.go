@@ -0,0 +1,126 @@
+package blockchain
+
+import (
+	"math/big"
+	"time"
+	"unsafe"
+)
+
+// A snapshot of a blockchain's operational metrics, as returned by Metrics. The fields are
+// plain numbers, so that callers can export them to Prometheus or any other monitoring system
+// without this package depending on it.
+type Metrics struct {
+	ChainLength      int           // gauge: number of blocks, including the genesis block
+	Transactions     int           // gauge: number of user transactions on the chain, excluding coinbase transactions
+	Difficulty       int           // gauge: the difficulty new blocks are mined at
+	MempoolSize      int           // gauge: number of transactions waiting in the mempool
+	SideBlocks       int           // gauge: number of known blocks on side branches
+	BlocksMined      uint64        // counter: number of blocks mined through this Blockchain value
+	LastMineDuration time.Duration // gauge: how long mining the most recent block took
+}
+
+// The bookkeeping behind Metrics.
+type metricsState struct {
+	blocksMined      uint64
+	lastMineDuration time.Duration
+	countedLen       int    // the chain length up to which transactions have been counted
+	countedTip       string // the hash of the last counted block
+	transactions     int    // the number of user transactions in the counted blocks
+}
+
+// This method returns the current metrics of the blockchain. It is cheap to call repeatedly:
+// transactions are counted incrementally, so only blocks appended since the previous call are
+// read, unless the chain has been reorganized or replaced in the meantime.
+func (b *Blockchain) Metrics() Metrics {
+	b.lock()
+	defer b.mu.Unlock()
+	b.countTransactions()
+	return Metrics{
+		ChainLength:      b.store.Len(),
+		Transactions:     b.metrics.transactions,
+		Difficulty:       b.difficulty,
+		MempoolSize:      len(b.pending),
+		SideBlocks:       len(b.sideBlocks),
+		BlocksMined:      b.metrics.blocksMined,
+		LastMineDuration: b.metrics.lastMineDuration,
+	}
+}
+
+// This method brings the transaction count of the metrics up to date with the chain. The
+// caller must hold the exclusive lock.
+func (b *Blockchain) countTransactions() {
+	m := &b.metrics
+	if m.countedLen > b.store.Len() || (m.countedLen > 0 && b.hashAt(m.countedLen-1) != m.countedTip) {
+		*m = metricsState{blocksMined: m.blocksMined, lastMineDuration: m.lastMineDuration}
+	}
+	for i, block := range b.blocks(m.countedLen) {
+		for _, tx := range block.transactions {
+			if tx.From != CoinbaseAddress {
+				m.transactions++
+			}
+		}
+		m.countedLen = i + 1
+		m.countedTip = block.hash
+	}
+}
+
+// This method returns the hash of the block at the given index, or "" if the block store
+// fails. The caller must hold the lock.
+func (b *Blockchain) hashAt(index int) string {
+	block, err := b.store.Get(index)
+	if err != nil {
+		return ""
+	}
+	return block.hash
+}
+
+// This method records a successfully mined block in the metrics. The caller must hold the
+// exclusive lock.
+func (b *Blockchain) recordMined(elapsed time.Duration) {
+	b.metrics.blocksMined++
+	b.metrics.lastMineDuration = elapsed
+}
+
+// This method returns a rough estimate, in bytes, of the memory the chain's blocks take: a
+// fixed overhead per block and per transaction, plus the hashes, keys and signatures they
+// hold, plus twice the marshaled size of each block's data, for the data itself and the copy
+// cached for hashing. Blocks on side branches and transactions in the mempool are included.
+// The estimate is not exact, but it grows with the number of blocks and the size of their data
+// like the real usage does, which helps to decide when to switch to a FileStore. For a chain
+// kept in a FileStore, it estimates what the chain would take if it were held in memory.
+func (b *Blockchain) ApproxMemoryBytes() int64 {
+	b.rlock()
+	defer b.mu.RUnlock()
+	var total int64
+	for _, block := range b.blocks(0) {
+		total += block.approxMemoryBytes()
+	}
+	for _, block := range b.sideBlocks {
+		total += block.approxMemoryBytes()
+	}
+	for _, tx := range b.pending {
+		total += tx.approxMemoryBytes()
+	}
+	return total
+}
+
+// This method returns the estimated memory the block takes, as summed by ApproxMemoryBytes.
+func (b Block) approxMemoryBytes() int64 {
+	data := b.dataJSON
+	if data == nil {
+		data, _ = canonicalJSON(b.data)
+	}
+	size := int64(unsafe.Sizeof(b)) + int64(len(b.hash)+len(b.previousHash)+len(b.merkleRoot)+2*len(data))
+	if b.target != nil {
+		size += int64(len(b.target.Bits())) * int64(unsafe.Sizeof(big.Word(0)))
+	}
+	for _, tx := range b.transactions {
+		size += tx.approxMemoryBytes()
+	}
+	return size
+}
+
+// This method returns the estimated memory the transaction takes, as summed by ApproxMemoryBytes.
+func (tx Transaction) approxMemoryBytes() int64 {
+	return int64(unsafe.Sizeof(tx)) + int64(len(tx.From)+len(tx.To)+len(tx.PublicKey)+len(tx.Signature))
+}
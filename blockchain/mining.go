@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// A strategy for mining blocks, that is, for searching a proof of work value for which the
+// block's hash satisfies the proof-of-work rule. The chain prepares the block and calls Mine
+// with it and the difficulty it must be mined at; Mine must record that difficulty on the
+// block and leave it with a satisfying nonce, which is best set with TryNonce, or return an
+// error. If the block is mined below a target threshold (see SetTargetThreshold), TryNonce
+// applies that rule instead of the difficulty. Long searches should give up once MiningCancelled reports an error.
+// The chain checks the result, so a faulty Miner can't append an invalid block. Mine is
+// called without the chain's lock held, so the chain can be read while a block is mined.
+type Miner interface {
+	Mine(block *Block, difficulty int) error
+}
+
+// The default Miner, which tries one proof of work value after the other, starting at the
+// block's nonce (see SetStartNonce).
+type SequentialMiner struct{}
+
+// A Miner that splits the proof of work values among several goroutines, which search in
+// parallel and all stop as soon as one of them finds a solution. Worker i tries the values
+// start+i, start+i+n, start+i+2n and so on, where n is the number of workers, so the nonce
+// found is not necessarily the lowest one and differs from the one SequentialMiner finds.
+type ParallelMiner struct {
+	Workers int // the number of goroutines; 0 or less means runtime.NumCPU()
+}
+
+// What a Miner needs to know about the mining of a block, beyond the block itself.
+type miningSession struct {
+	ctx     context.Context  // aborts mining when done
+	newHash func() hash.Hash // the chain's hash algorithm
+}
+
+// This method sets the strategy used to mine new blocks, for example a ParallelMiner. Passing
+// nil restores the default SequentialMiner.
+func (b *Blockchain) SetMiner(m Miner) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.mining = m
+}
+
+// This method returns the strategy used to mine new blocks. The caller must hold the lock.
+func (b *Blockchain) miner() Miner {
+	if b.mining == nil {
+		return SequentialMiner{}
+	}
+	return b.mining
+}
+
+// This method sets the block's proof of work value, recomputes its hash with the chain's hash
+// algorithm, and reports whether the hash satisfies the rule the block is mined under. It is
+// meant for Miner implementations; on a block that is not being mined, the hash is computed
+// with SHA-256.
+func (b *Block) TryNonce(nonce int) bool {
+	b.pow = nonce
+	b.hash = b.calculateHash(b.miningHash())
+	return b.proofRule()(b.hash)
+}
+
+// This method returns an error once the mining of the block has been cancelled, for example
+// because the context passed to MineBlockContext is done, and nil otherwise. Miner
+// implementations check it regularly to give up on long searches.
+func (b Block) MiningCancelled() error {
+	if b.mining == nil {
+		return nil
+	}
+	return b.mining.ctx.Err()
+}
+
+// This method returns the hash algorithm the block is mined with.
+func (b Block) miningHash() func() hash.Hash {
+	if b.mining == nil {
+		return sha256.New
+	}
+	return b.mining.newHash
+}
+
+// This method implements Miner.
+func (SequentialMiner) Mine(block *Block, difficulty int) error {
+	block.difficulty = difficulty
+	ctx := context.Background()
+	if block.mining != nil {
+		ctx = block.mining.ctx
+	}
+	return block.mine(ctx, block.miningHash())
+}
+
+// This method implements Miner.
+func (m ParallelMiner) Mine(block *Block, difficulty int) error {
+	block.difficulty = difficulty
+	workers := m.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var (
+		found    atomic.Bool
+		solution Block
+		wg       sync.WaitGroup
+	)
+	start := block.pow
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			candidate := *block
+			satisfied := candidate.proofRule()
+			newHash := candidate.miningHash()
+			tries := 0
+			for nonce := start + w; !found.Load(); nonce += workers {
+				if tries%1024 == 0 && candidate.MiningCancelled() != nil {
+					return
+				}
+				tries++
+				candidate.pow = nonce
+				candidate.hash = candidate.calculateHash(newHash)
+				if satisfied(candidate.hash) {
+					if found.CompareAndSwap(false, true) {
+						solution = candidate
+					}
+					return
+				}
+				if nonce > math.MaxInt-workers {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if found.Load() {
+		*block = solution
+		return nil
+	}
+	if err := block.MiningCancelled(); err != nil {
+		return fmt.Errorf("%w: %w", ErrMiningCancelled, err)
+	}
+	return errors.New("mining aborted: proof of work overflowed")
+}
@@ -0,0 +1,204 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A Miner that counts its calls and mines with SequentialMiner, or fails with err if set.
+type countingMiner struct {
+	calls int
+	err   error
+}
+
+func (m *countingMiner) Mine(block *Block, difficulty int) error {
+	m.calls++
+	if m.err != nil {
+		return m.err
+	}
+	return SequentialMiner{}.Mine(block, difficulty)
+}
+
+func TestEstimateMineTimeUsesMiner(t *testing.T) {
+	b := CreateBlockchain(1)
+	miner := &countingMiner{}
+	b.SetMiner(miner)
+	if got := b.EstimateMineTime(1, 3); got <= 0 {
+		t.Fatalf("EstimateMineTime() = %v, want a positive duration", got)
+	}
+	if miner.calls != 3 {
+		t.Fatalf("the Miner was called %d times, want 3", miner.calls)
+	}
+	miner.err = errors.New("broken miner")
+	if got := b.EstimateMineTime(1, 3); got != 0 {
+		t.Fatalf("EstimateMineTime() = %v with a failing Miner, want 0", got)
+	}
+}
+
+func TestEstimateMineTimeUsesTargetThreshold(t *testing.T) {
+	b := CreateBlockchain(1)
+	// Every hash is below this target, so a block is mined at once, while mining at the
+	// maximum difficulty would never finish.
+	everything := new(big.Int).Lsh(big.NewInt(1), 256)
+	b.SetTargetThreshold(everything)
+	if got := b.EstimateMineTime(MaxDifficulty, 1); got <= 0 {
+		t.Fatalf("EstimateMineTime() = %v, want a positive duration", got)
+	}
+}
+
+func TestParallelMiner(t *testing.T) {
+	b := CreateBlockchain(2)
+	b.SetMiner(ParallelMiner{Workers: 4})
+	block := mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	if !strings.HasPrefix(block.Hash(), "00") {
+		t.Fatalf("hash %s doesn't satisfy difficulty 2", block.Hash())
+	}
+
+	target := new(big.Int).Lsh(big.NewInt(1), 248)
+	b.SetTargetThreshold(target)
+	block = mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	if hash, _ := new(big.Int).SetString(block.Hash(), 16); hash.Cmp(target) >= 0 {
+		t.Fatalf("hash %s is not below the target %x", block.Hash(), target)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMinersHonorTheDifficulty(t *testing.T) {
+	for name, miner := range map[string]Miner{"sequential": SequentialMiner{}, "parallel": ParallelMiner{Workers: 4}} {
+		block := Block{timestamp: GenesisEpoch()}
+		if err := miner.Mine(&block, 2); err != nil {
+			t.Fatal(err)
+		}
+		if block.Difficulty() != 2 || !strings.HasPrefix(block.Hash(), "00") {
+			t.Errorf("%s: mined hash %s at difficulty %d, want difficulty 2", name, block.Hash(), block.Difficulty())
+		}
+	}
+}
+
+func TestParallelMinerCancellation(t *testing.T) {
+	// No hash satisfies this difficulty, so mining only ends when it is cancelled.
+	b := CreateBlockchain(MaxDifficulty)
+	b.SetMiner(ParallelMiner{Workers: 4})
+	if err := b.QueueTransaction(Transaction{From: "alice", To: "bob", Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := b.MineBlockContext(ctx); !errors.Is(err, ErrMiningCancelled) {
+		t.Fatalf("MineBlockContext() = %v, want ErrMiningCancelled", err)
+	}
+	if b.Len() != 1 || len(b.PendingTransactions()) != 1 {
+		t.Fatalf("cancelled mining left %d blocks and %d queued transactions, want 1 and 1", b.Len(), len(b.PendingTransactions()))
+	}
+}
+
+func BenchmarkMiners(b *testing.B) {
+	for _, bench := range []struct {
+		name  string
+		miner Miner
+	}{
+		{"sequential", SequentialMiner{}},
+		{"parallel", ParallelMiner{}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				block := Block{height: i + 1, timestamp: GenesisEpoch(), difficulty: 4}
+				if err := bench.miner.Mine(&block, 4); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// This function mines one block holding a fixed transaction on a chain with a fixed genesis
+// block and a frozen clock, starting at the given nonce.
+func deterministicBlock(t *testing.T, startNonce int) Block {
+	t.Helper()
+	b := CreateBlockchainWithGenesis(2, map[string]interface{}{"name": "deterministic"}, GenesisEpoch())
+	clock := GenesisEpoch().Add(time.Minute)
+	b.SetClock(func() time.Time { return clock })
+	b.SetStartNonce(startNonce)
+	return mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1, Timestamp: clock})
+}
+
+func TestDeterministicMining(t *testing.T) {
+	const (
+		hash  = "007af3d590fb7345a0af4df75ccc00a731b92fac3021f5ac6899e2da15070cf0"
+		nonce = 45
+	)
+	for run := 0; run < 2; run++ {
+		if block := deterministicBlock(t, 0); block.Hash() != hash || block.Nonce() != nonce {
+			t.Fatalf("mined block with hash %s and nonce %d, want %s and %d", block.Hash(), block.Nonce(), hash, nonce)
+		}
+	}
+	if block := deterministicBlock(t, nonce); block.Hash() != hash {
+		t.Fatalf("mining from the known nonce found hash %s, want %s", block.Hash(), hash)
+	}
+	if block := deterministicBlock(t, nonce+1); block.Nonce() <= nonce {
+		t.Fatalf("mining from nonce %d found nonce %d", nonce+1, block.Nonce())
+	}
+}
+
+// A Miner whose first call blocks until release is closed, after signalling on started.
+type blockingMiner struct {
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (m *blockingMiner) Mine(block *Block, difficulty int) error {
+	if m.calls.Add(1) == 1 {
+		close(m.started)
+		<-m.release
+	}
+	return SequentialMiner{}.Mine(block, difficulty)
+}
+
+func TestMiningDoesNotBlockTheChain(t *testing.T) {
+	b := CreateBlockchain(1)
+	miner := &blockingMiner{started: make(chan struct{}), release: make(chan struct{})}
+	b.SetMiner(miner)
+	done := make(chan error)
+	go func() {
+		done <- b.AddTransaction("alice", "bob", 1)
+	}()
+	<-miner.started
+
+	// Readers and other miners proceed while the first block is being mined.
+	if got := b.Len(); got != 1 {
+		t.Fatalf("Len() while mining = %d, want 1", got)
+	}
+	b.Snapshot()
+	b.Metrics()
+	if err := b.AddTransaction("carol", "dave", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first block no longer extends the tip, so it is mined again on top of it.
+	close(miner.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := miner.calls.Load(); got != 3 {
+		t.Errorf("Mine() called %d times, want 3", got)
+	}
+	if got := b.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for account, want := range map[string]float64{"bob": 1, "dave": 2} {
+		if got := b.BalanceOf(account); got != want {
+			t.Errorf("BalanceOf(%q) = %v, want %v", account, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package blockchain
+
+import (
+	"fmt"
+	"math"
+)
+
+// An exact amount of money, counted in the smallest unit: one hundred-millionth of a coin.
+// Balances and totals are summed as Money so that, for example, a thousand transactions of
+// 0.01 add up to exactly 10, which summing float64 values would not guarantee.
+type Money int64
+
+// The number of decimal places a Money value can represent.
+const MoneyDecimals = 8
+
+// The number of smallest units in one coin.
+const moneyScale = 1e8
+
+// This function converts an amount to Money. An error is returned if the amount is not a
+// finite number, is too large to represent, or has more than MoneyDecimals decimal places.
+func ToMoney(amount float64) (Money, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("amount %v is not a finite number", amount)
+	}
+	units := math.Round(amount * moneyScale)
+	if math.Abs(units) >= math.MaxInt64 {
+		return 0, fmt.Errorf("amount %v is too large", amount)
+	}
+	if units/moneyScale != amount {
+		return 0, fmt.Errorf("amount %v has more than %d decimal places", amount, MoneyDecimals)
+	}
+	return Money(units), nil
+}
+
+// This function converts an amount that is already known to be valid to Money, rounding it to
+// the nearest smallest unit.
+func moneyOf(amount float64) Money {
+	return Money(math.Round(amount * moneyScale))
+}
+
+// This method converts the amount back to a float64 number of coins.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+// This method formats the amount with all of its decimal places.
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, m/moneyScale, m%moneyScale)
+}
@@ -0,0 +1,55 @@
+package blockchain
+
+import "testing"
+
+func TestMoneySumsExactly(t *testing.T) {
+	b := CreateBlockchain(1)
+	var floatSum float64
+	for i := 0; i < 1000; i++ {
+		if err := b.QueueTransaction(Transaction{From: "alice", To: "bob", Amount: 0.01, Nonce: uint64(i)}); err != nil {
+			t.Fatal(err)
+		}
+		floatSum += 0.01
+		if i%100 == 99 {
+			if _, err := b.MineBlock(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if floatSum == 10 {
+		t.Fatal("summing float64 values was exact, so the test proves nothing")
+	}
+	if got := b.BalanceOf("bob"); got != 10 {
+		t.Errorf("BalanceOf(bob) = %v, want exactly 10", got)
+	}
+	if got := b.BalanceOf("alice"); got != -10 {
+		t.Errorf("BalanceOf(alice) = %v, want exactly -10", got)
+	}
+	if got := b.Stats().TotalAmount; got != 10 {
+		t.Errorf("Stats().TotalAmount = %v, want exactly 10", got)
+	}
+}
+
+func TestToMoney(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+		ok     bool
+	}{
+		{0.01, "0.01000000", true},
+		{-2.5, "-2.50000000", true},
+		{0.00000001, "0.00000001", true},
+		{0.000000001, "", false},
+		{1e300, "", false},
+	}
+	for _, test := range tests {
+		money, err := ToMoney(test.amount)
+		if (err == nil) != test.ok {
+			t.Errorf("ToMoney(%v) = %v, want ok %v", test.amount, err, test.ok)
+			continue
+		}
+		if test.ok && money.String() != test.want {
+			t.Errorf("ToMoney(%v) = %s, want %s", test.amount, money, test.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"runtime"
+	"sync"
+)
+
+// The number of blocks validate reads from the store and checks in parallel at a time. This
+// bounds the memory used when validating chains kept in a FileStore.
+const validationBatchSize = 4096
+
+// Below this many blocks, checking in parallel costs more than it saves.
+const minParallelBlocks = 64
+
+// The number of workers checkBlocksParallel spreads a batch over: one per CPU.
+var validationWorkers = runtime.NumCPU()
+
+// This method runs checkBlock on every block of the batch, whose first block is at index
+// start, spreading the work over validationWorkers workers. The returned slice holds the
+// error for each block of the batch, in order.
+func (b *Blockchain) checkBlocksParallel(start int, batch []Block) []error {
+	errs := make([]error, len(batch))
+	workers := validationWorkers
+	if workers < 2 || len(batch) < minParallelBlocks {
+		for j, block := range batch {
+			errs[j] = b.checkBlock(start+j, block)
+		}
+		return errs
+	}
+	var wg sync.WaitGroup
+	chunk := (len(batch) + workers - 1) / workers
+	for lo := 0; lo < len(batch); lo += chunk {
+		hi := min(lo+chunk, len(batch))
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for j := lo; j < hi; j++ {
+				errs[j] = b.checkBlock(start+j, batch[j])
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+	return errs
+}
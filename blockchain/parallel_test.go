@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// This function runs f with validation spread over the given number of workers.
+func withValidationWorkers(workers int, f func()) {
+	saved := validationWorkers
+	validationWorkers = workers
+	defer func() { validationWorkers = saved }()
+	f()
+}
+
+func TestParallelValidationMatchesSerial(t *testing.T) {
+	b := longChain(t, 500, func(i int) (string, string) {
+		return fmt.Sprintf("account%d", i%10), fmt.Sprintf("account%d", (i+1)%10)
+	})
+	// The blocks after the first tampered one still fail, so the first must be reported.
+	for _, index := range []int{300, 420} {
+		b.store.(*MemoryStore).blocks[index].transactions[0].Amount = 1000
+	}
+	var serial, parallel error
+	withValidationWorkers(1, func() { serial = b.Validate() })
+	withValidationWorkers(8, func() { parallel = b.Validate() })
+	if serial == nil || parallel == nil || serial.Error() != parallel.Error() {
+		t.Fatalf("Validate() = %v serially and %v in parallel, want the same error", serial, parallel)
+	}
+	if want := "block 300:"; !strings.Contains(serial.Error(), want) {
+		t.Fatalf("Validate() = %v, want an error for block 300", serial)
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	chain := longChain(b, 100000, func(i int) (string, string) {
+		return fmt.Sprintf("account%d", i%100), fmt.Sprintf("account%d", (i+1)%100)
+	})
+	for _, bench := range []struct {
+		name    string
+		workers int
+	}{
+		{"serial", 1},
+		{"parallel", validationWorkers},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			withValidationWorkers(bench.workers, func() {
+				for i := 0; i < b.N; i++ {
+					if err := chain.Validate(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package blockchain
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+)
+
+// The serializable form of a block. All fields of Block are unexported, so this is what
+// actually gets written and read by Block's JSON and gob encoding methods.
+// The timestamp is encoded in UTC as RFC 3339 with nanoseconds so that it round-trips exactly
+// and calculateHash() on a decoded block reproduces the stored hash.
+// The encoded block is enough to verify its hash independently: the hash is the hex digest,
+// with the chain's hash algorithm, of the concatenation of the decimal height, previousHash,
+// merkleRoot, the compact JSON encoding of data with the keys of every object sorted, the
+// timestamp exactly as encoded, the decimal difficulty, a slash, the target in lowercase hex
+// (empty if there is none), a slash, and the decimal pow.
+type blockWire struct {
+	Hash         string                 `json:"hash"`
+	PreviousHash string                 `json:"previousHash"`
+	Data         map[string]interface{} `json:"data"`
+	Transactions []Transaction          `json:"transactions,omitempty"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Pow          int                    `json:"pow"`
+	MerkleRoot   string                 `json:"merkleRoot,omitempty"`
+	Difficulty   int                    `json:"difficulty,omitempty"`
+	Target       *big.Int               `json:"target,omitempty"`
+	Height       int                    `json:"height"`
+}
+
+// The serializable form of a blockchain.
+type blockchainWire struct {
+	Difficulty    int     `json:"difficulty"`
+	HashAlgorithm string  `json:"hashAlgorithm,omitempty"`
+	Finalized     int     `json:"finalized,omitempty"`
+	MiningReward  float64 `json:"miningReward,omitempty"`
+	MinDifficulty int     `json:"minDifficulty,omitempty"`
+	Blocks        []Block `json:"blocks"`
+}
+
+// This method implements json.Marshaler.
+func (b Block) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.wire())
+}
+
+// This method implements json.Unmarshaler.
+func (b *Block) UnmarshalJSON(content []byte) error {
+	var decoded blockWire
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		return err
+	}
+	*b = decoded.block()
+	return nil
+}
+
+// This method returns the serializable form of the block.
+func (b Block) wire() blockWire {
+	return blockWire{
+		Hash:         b.hash,
+		PreviousHash: b.previousHash,
+		Data:         b.data,
+		Transactions: b.transactions,
+		Timestamp:    b.timestamp.UTC(),
+		Pow:          b.pow,
+		MerkleRoot:   b.merkleRoot,
+		Difficulty:   b.difficulty,
+		Target:       b.target,
+		Height:       b.height,
+	}
+}
+
+// This method rebuilds the block from its serializable form.
+func (w blockWire) block() Block {
+	block := Block{
+		transactions: w.Transactions,
+		hash:         w.Hash,
+		previousHash: w.PreviousHash,
+		timestamp:    w.Timestamp,
+		pow:          w.Pow,
+		merkleRoot:   w.MerkleRoot,
+		difficulty:   w.Difficulty,
+		target:       w.Target,
+		height:       w.Height,
+	}
+	block.setData(w.Data)
+	return block
+}
+
+// This method writes the blockchain, including the genesis block, the difficulty, the hash
+// algorithm, the finalized height, the mining reward and the minimum difficulty, to the given
+// path as JSON. An existing file is overwritten.
+func (b *Blockchain) SaveToFile(path string) error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	saved, err := b.wire()
+	if err != nil {
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	content, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// This function reads a blockchain previously written by SaveToFile.
+// The reconstructed chain is validated, and an error is returned if it has been tampered with.
+func LoadFromFile(path string) (Blockchain, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Blockchain{}, err
+	}
+	return ImportChain(content)
+}
+
+// This function decodes a blockchain from JSON in the format written by SaveToFile. The
+// result is validated like in Validate, including the genesis block, every hash and every
+// proof of work. An error is returned if the data can't be decoded or the chain is not valid,
+// and a partially valid chain is never returned.
+// The chain is only checked for consistency with itself: its genesis block, hash algorithm
+// and minimum difficulty are taken from the data, so anyone can produce a valid chain by
+// mining one from scratch at a trivial difficulty. For chain data from an untrusted source,
+// such as a peer, use ImportChainWithGenesis.
+func ImportChain(data []byte) (Blockchain, error) {
+	return ImportChainWithGenesis(data, "", 0)
+}
+
+// This function is like ImportChain, but is the safe entry point for untrusted chain data:
+// the chain's genesis block must have the given hash, which pins the network's genesis block
+// and hash algorithm, and no block may be mined below the given minimum difficulty, or the
+// minimum recorded in the data if that is higher (see SetMinDifficulty). An empty genesis
+// hash accepts any genesis block, like ImportChain.
+func ImportChainWithGenesis(data []byte, genesisHash string, minDifficulty int) (Blockchain, error) {
+	var saved blockchainWire
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return Blockchain{}, fmt.Errorf("decode blockchain: %w", err)
+	}
+	return saved.restore(genesisHash, minDifficulty)
+}
+
+// This method writes the blockchain to the given path like SaveToFile, but as compact JSON
+// compressed with gzip, which shrinks large chains considerably since their blocks repeat the
+// same keys and similar values. An existing file is overwritten. See LoadCompressed.
+func (b *Blockchain) SaveCompressed(path string) error {
+	b.rlock()
+	saved, err := b.wire()
+	b.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(file)
+	if err := json.NewEncoder(compressed).Encode(saved); err != nil {
+		file.Close()
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("encode blockchain: %w", err)
+	}
+	return file.Close()
+}
+
+// This function reads a blockchain previously written by SaveCompressed.
+// The reconstructed chain is validated, and an error is returned if it has been tampered with.
+func LoadCompressed(path string) (Blockchain, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Blockchain{}, err
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return Blockchain{}, fmt.Errorf("decode blockchain: %w", err)
+	}
+	defer compressed.Close()
+	var saved blockchainWire
+	if err := json.NewDecoder(compressed).Decode(&saved); err != nil {
+		return Blockchain{}, fmt.Errorf("decode blockchain: %w", err)
+	}
+	return saved.restore("", 0)
+}
+
+// This method writes the blocks of the chain to w as newline-delimited JSON, one block per
+// line, starting with the genesis block. This is the format of a FileStore, and can be read
+// back block by block with LoadStreaming.
+func (b *Blockchain) SaveStreaming(w io.Writer) error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	encoder := json.NewEncoder(w)
+	for i := 0; i < b.store.Len(); i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", i, err)
+		}
+		if err := encoder.Encode(block); err != nil {
+			return fmt.Errorf("encode block %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// This function reads a chain of newline-delimited JSON blocks, as written by SaveStreaming
+// or kept by a FileStore, without buffering the whole input first. Every block is checked as
+// soon as it has been decoded, like in Validate, so that loading fails fast with the index of
+// the first bad block. The first block is taken as the genesis block. Blocks are hashed with
+// SHA-256, and new blocks are mined at the difficulty (or target threshold) of the last block.
+// The chain is validated as paying no mining reward; use LoadStreamingWithReward to load a
+// rewarded chain.
+func LoadStreaming(r io.Reader) (Blockchain, error) {
+	return LoadStreamingWithReward(r, 0)
+}
+
+// This function works like LoadStreaming, except that the mining reward is set (see
+// SetMiningReward) before the blocks are validated, since SaveStreaming records only the blocks.
+func LoadStreamingWithReward(r io.Reader, miningReward float64) (Blockchain, error) {
+	decoder := json.NewDecoder(r)
+	var loaded Blockchain
+	var previousBlock Block
+	seen := make(map[string]int)
+	for i := 0; ; i++ {
+		var block Block
+		if err := decoder.Decode(&block); err == io.EOF {
+			break
+		} else if err != nil {
+			return Blockchain{}, fmt.Errorf("decode block %d: %w", i, err)
+		}
+		if i == 0 {
+			loaded = newBlockchain(0, &MemoryStore{blocks: []Block{block}}, nil)
+			loaded.miningReward = miningReward
+			if err := loaded.validate(); err != nil {
+				return Blockchain{}, fmt.Errorf("loaded blockchain is not valid: %w", err)
+			}
+		} else {
+			if err := loaded.validateBlock(i, previousBlock, block, loaded.clock().Add(loaded.maxClockSkew)); err != nil {
+				return Blockchain{}, fmt.Errorf("loaded blockchain is not valid: %w: %w", ErrChainTampered, err)
+			}
+			if err := recordIDs(seen, i, block); err != nil {
+				return Blockchain{}, fmt.Errorf("loaded blockchain is not valid: %w: block %d: %w", ErrChainTampered, i, err)
+			}
+			loaded.store.Append(block)
+		}
+		previousBlock = block
+	}
+	if loaded.chainState == nil {
+		return Blockchain{}, errors.New("decode blockchain: no genesis block")
+	}
+	loaded.difficulty = clampDifficulty(previousBlock.difficulty, MaxDifficulty)
+	loaded.target = copyTarget(previousBlock.target)
+	return loaded, nil
+}
+
+// This method rebuilds a blockchain from its decoded form. The result is validated, and an
+// error is returned if it has been tampered with. If genesisHash isn't empty, the genesis
+// block must have that hash; no block may be mined below minDifficulty either way.
+func (saved blockchainWire) restore(genesisHash string, minDifficulty int) (Blockchain, error) {
+	if len(saved.Blocks) == 0 {
+		return Blockchain{}, errors.New("decode blockchain: no genesis block")
+	}
+	if genesisHash != "" && saved.Blocks[0].hash != genesisHash {
+		return Blockchain{}, fmt.Errorf("decode blockchain: %w: genesis block %s is not %s", ErrChainTampered, shortHash(saved.Blocks[0].hash), shortHash(genesisHash))
+	}
+	newHash, err := lookupHashAlgorithm(saved.HashAlgorithm)
+	if err != nil {
+		return Blockchain{}, fmt.Errorf("decode blockchain: %w", err)
+	}
+	loaded := newBlockchain(saved.Difficulty, &MemoryStore{blocks: saved.Blocks}, newHash)
+	loaded.miningReward = saved.MiningReward
+	loaded.minDifficulty = clampDifficulty(max(saved.MinDifficulty, minDifficulty), loaded.maxDifficulty())
+	loaded.difficulty = max(loaded.difficulty, loaded.minDifficulty)
+	if err := loaded.validate(); err != nil {
+		return Blockchain{}, fmt.Errorf("loaded blockchain is not valid: %w", err)
+	}
+	if saved.Finalized < 0 || saved.Finalized >= len(saved.Blocks) {
+		return Blockchain{}, fmt.Errorf("decode blockchain: finalized height %d out of range [0, %d]", saved.Finalized, len(saved.Blocks)-1)
+	}
+	loaded.finalized = saved.Finalized
+	return loaded, nil
+}
+
+// This method returns the serializable form of the blockchain. The caller must hold the lock.
+func (b *Blockchain) wire() (blockchainWire, error) {
+	name, err := hashAlgorithmName(b.hashFunc())
+	if err != nil {
+		return blockchainWire{}, err
+	}
+	if name == DefaultHashAlgorithm {
+		name = ""
+	}
+	blocks, err := b.collect()
+	if err != nil {
+		return blockchainWire{}, err
+	}
+	return blockchainWire{
+		Difficulty:    b.difficulty,
+		HashAlgorithm: name,
+		Finalized:     b.finalized,
+		MiningReward:  b.miningReward,
+		MinDifficulty: b.minDifficulty,
+		Blocks:        blocks,
+	}, nil
+}
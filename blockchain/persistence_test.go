@@ -0,0 +1,225 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// This function re-mines every block after the genesis block at the given difficulty, as a
+// forger would to rewrite the chain cheaply, keeping the links between the blocks intact.
+func remine(t *testing.T, b *Blockchain, difficulty int) {
+	t.Helper()
+	blocks := b.store.(*MemoryStore).blocks
+	for i := 1; i < len(blocks); i++ {
+		tamper(t, b, i, func(block *Block) {
+			block.previousHash = blocks[i-1].hash
+			block.difficulty = difficulty
+		})
+	}
+}
+
+func TestLoadFromFileRejectsLoweredProofOfWork(t *testing.T) {
+	b := CreateBlockchain(2)
+	if err := b.SetMinDifficulty(2); err != nil {
+		t.Fatal(err)
+	}
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 1})
+	path := filepath.Join(t.TempDir(), "chain.json")
+	if err := b.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile() of the honest chain = %v", err)
+	}
+	remine(t, &b, 0)
+	if err := b.Validate(); err == nil {
+		t.Fatal("Validate() accepted blocks re-mined below the minimum difficulty")
+	}
+	if err := b.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); !errors.Is(err, ErrChainTampered) {
+		t.Fatalf("LoadFromFile() = %v, want ErrChainTampered", err)
+	}
+}
+
+func TestSetMinDifficulty(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	if err := b.SetMinDifficulty(2); err == nil {
+		t.Fatal("SetMinDifficulty() accepted a minimum above a block on the chain")
+	}
+	if err := b.SetMinDifficulty(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetDifficulty(0); err == nil {
+		t.Fatal("SetDifficulty() accepted a difficulty below the minimum")
+	}
+	if err := b.SetMinDifficulty(-1); err == nil {
+		t.Fatal("SetMinDifficulty() accepted a negative difficulty")
+	}
+}
+
+func TestImportChainWithGenesis(t *testing.T) {
+	b := CreateBlockchainWithGenesis(2, nil, GenesisEpoch())
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	honest, err := json.Marshal(mustWire(t, &b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesisHash := b.genesisBlock.hash
+	if _, err := ImportChainWithGenesis(honest, genesisHash, 2); err != nil {
+		t.Fatalf("ImportChainWithGenesis() of the honest chain = %v", err)
+	}
+
+	// A chain mined from scratch, at difficulty 0, on a genesis block of the attacker's own.
+	forged := CreateBlockchain(0)
+	mineTransactions(t, &forged, Transaction{From: "alice", To: "mallory", Amount: 1000})
+	data, err := json.Marshal(mustWire(t, &forged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportChain(data); err != nil {
+		t.Fatalf("ImportChain() = %v, want the self-consistent chain accepted", err)
+	}
+	if _, err := ImportChainWithGenesis(data, genesisHash, 2); !errors.Is(err, ErrChainTampered) {
+		t.Fatalf("ImportChainWithGenesis() of a foreign genesis = %v, want ErrChainTampered", err)
+	}
+
+	// The honest chain re-mined at difficulty 0 on the right genesis block.
+	remine(t, &b, 0)
+	data, err = json.Marshal(mustWire(t, &b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportChainWithGenesis(data, genesisHash, 2); !errors.Is(err, ErrChainTampered) {
+		t.Fatalf("ImportChainWithGenesis() of a re-mined chain = %v, want ErrChainTampered", err)
+	}
+}
+
+// This function returns the serializable form of the blockchain, failing the test on error.
+func mustWire(t *testing.T, b *Blockchain) blockchainWire {
+	t.Helper()
+	saved, err := b.wire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+// This test recomputes block hashes from the JSON form of a chain alone, following the recipe
+// documented on blockWire, as an independent auditor would.
+func TestBlockHashFromJSON(t *testing.T) {
+	b := CreateBlockchainWithGenesis(1, map[string]interface{}{"note": "genesis", "a": []interface{}{1, "x"}}, GenesisEpoch())
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1.5})
+	data, err := json.Marshal(mustWire(t, &b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chain struct {
+		Blocks []struct {
+			Hash         string          `json:"hash"`
+			PreviousHash string          `json:"previousHash"`
+			Data         json.RawMessage `json:"data"`
+			Timestamp    string          `json:"timestamp"`
+			Pow          int             `json:"pow"`
+			MerkleRoot   string          `json:"merkleRoot"`
+			Difficulty   int             `json:"difficulty"`
+			Target       *big.Int        `json:"target"`
+			Height       int             `json:"height"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(data, &chain); err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Blocks) != 2 {
+		t.Fatalf("the JSON holds %d blocks, want 2", len(chain.Blocks))
+	}
+	for _, block := range chain.Blocks {
+		// encoding/json sorts the keys of decoded objects when it encodes them back.
+		var decoded interface{}
+		if err := json.Unmarshal(block.Data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		sorted, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var target string
+		if block.Target != nil {
+			target = block.Target.Text(16)
+		}
+		preimage := strconv.Itoa(block.Height) + block.PreviousHash + block.MerkleRoot + string(sorted) +
+			block.Timestamp + strconv.Itoa(block.Difficulty) + "/" + target + "/" + strconv.Itoa(block.Pow)
+		if got := fmt.Sprintf("%x", sha256.Sum256([]byte(preimage))); got != block.Hash {
+			t.Errorf("block %d: hash recomputed from JSON = %s, want %s", block.Height, got, block.Hash)
+		}
+	}
+}
+
+func TestSaveCompressed(t *testing.T) {
+	b := CreateBlockchain(1)
+	for i := 0; i < 50; i++ {
+		mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1, Nonce: uint64(i)})
+	}
+	dir := t.TempDir()
+	plain, compressed := filepath.Join(dir, "chain.json"), filepath.Join(dir, "chain.json.gz")
+	if err := b.SaveToFile(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SaveCompressed(compressed); err != nil {
+		t.Fatal(err)
+	}
+	plainInfo, err := os.Stat(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressedInfo, err := os.Stat(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressedInfo.Size()*3 > plainInfo.Size() {
+		t.Errorf("compressed file has %d bytes, want less than a third of the %d bytes of the JSON file", compressedInfo.Size(), plainInfo.Size())
+	}
+	loaded, err := LoadCompressed(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ChainHash() != b.ChainHash() {
+		t.Fatal("LoadCompressed() returned a different chain")
+	}
+}
+
+func TestBlockJSONRoundTrip(t *testing.T) {
+	b := CreateBlockchain(2)
+	// A timestamp with nanoseconds in another time zone, which must survive the round trip.
+	stamp := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("CEST", 2*60*60))
+	b.SetClock(func() time.Time { return stamp })
+	mined := mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1.25, Timestamp: stamp})
+	data, err := json.Marshal(mined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Block
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.calculateHash(sha256.New); got != mined.Hash() {
+		t.Fatalf("hash recomputed after a JSON round trip = %s, want %s", got, mined.Hash())
+	}
+	if decoded.PreviousHash() != mined.PreviousHash() || decoded.Nonce() != mined.Nonce() || !decoded.Timestamp().Equal(mined.Timestamp()) {
+		t.Fatalf("decoded block %v, want %v", decoded, mined)
+	}
+}
@@ -0,0 +1,46 @@
+package blockchain
+
+import "fmt"
+
+// This method returns the balance of an account as of the block at the given height, computed
+// like BalanceOf over the blocks up to and including that height, together with the hashes of
+// those blocks in chain order, starting with the genesis block. The last hash pins the chain
+// state the balance was computed against: a light client compares it with the block hash it
+// trusts at that height, and the preceding hashes let it check the previousHash links of the
+// blocks it downloads step by step back to the genesis block.
+// An error is returned if the height is out of range, or if the block store fails.
+func (b *Blockchain) BalanceProofAt(account string, height int) (float64, []string, error) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	if height < 0 || height >= b.store.Len() {
+		return 0, nil, fmt.Errorf("%w: height %d out of range [0, %d]", ErrBlockNotFound, height, b.store.Len()-1)
+	}
+	balances := map[string]Money{account: b.allocations()[account]}
+	hashes := make([]string, 0, height+1)
+	for i := 0; i <= height; i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return 0, nil, err
+		}
+		hashes = append(hashes, block.hash)
+		if i == 0 {
+			continue
+		}
+		for _, tx := range block.transactions {
+			if tx.From == account || tx.To == account {
+				applyTx(balances, tx)
+			}
+		}
+	}
+	return balances[account].Float64(), hashes, nil
+}
+
+// This method returns the balance of an account as of the block at the given height, counting
+// only the blocks on the main chain up to and including that height, for example to show a
+// historical balance as of block N. Side branches are never counted, so after a reorganization
+// the balance follows the new main chain. The genesis block contributes only its allocations
+// (see CreateBlockchainWithAllocations). An error is returned if the height is out of range.
+func (b *Blockchain) BalanceAtHeight(account string, height int) (float64, error) {
+	balance, _, err := b.BalanceProofAt(account, height)
+	return balance, err
+}
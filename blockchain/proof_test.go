@@ -0,0 +1,55 @@
+package blockchain
+
+import "testing"
+
+func TestBalanceComputationsAgree(t *testing.T) {
+	b := CreateBlockchainWithAllocations(1, map[string]float64{"alice": 100, "bob": 10})
+	b.SetMinerAddress("miner")
+	b.SetMiningReward(5)
+	mineTransactions(t, &b,
+		Transaction{From: "alice", To: "bob", Amount: 20, Fee: 0.5},
+		Transaction{From: "bob", To: "bob", Amount: 3, Fee: 0.25},
+	)
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 7.5, Fee: 1})
+	want := map[string]float64{"alice": 79.5, "bob": 21.25, "carol": 7.5, "miner": 11.75}
+	top := make(map[string]float64)
+	for _, balance := range b.TopBalances(10) {
+		top[balance.Account] = balance.Balance
+	}
+	tip := b.store.Len() - 1
+	for account, balance := range want {
+		checked, err := b.BalanceOfChecked(account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		atTip, proof, err := b.BalanceProofAt(account, tip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof) != tip+1 || proof[tip] != b.LatestBlock().Hash() {
+			t.Errorf("BalanceProofAt(%q) doesn't end with the tip's hash", account)
+		}
+		for name, got := range map[string]float64{
+			"BalanceOf":        b.BalanceOf(account),
+			"BalanceOfChecked": checked,
+			"BalanceProofAt":   atTip,
+			"TopBalances":      top[account],
+		} {
+			if got != balance {
+				t.Errorf("%s(%q) = %v, want %v", name, account, got, balance)
+			}
+		}
+	}
+	if len(top) != len(want) {
+		t.Errorf("TopBalances() = %v, want %d accounts", top, len(want))
+	}
+	b.EnableUTXOIndex()
+	for account, balance := range want {
+		if got := b.BalanceOf(account); got != balance {
+			t.Errorf("indexed BalanceOf(%q) = %v, want %v", account, got, balance)
+		}
+	}
+	if err := b.CheckConservation(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,196 @@
+package blockchain
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+// This method returns every transaction in which the account is either the "from" or the
+// "to" party, in chain order. The genesis block is skipped. If the account index is enabled
+// (see EnableAccountIndex), only the blocks holding the account's transactions are read.
+func (b *Blockchain) TransactionsFor(account string) []Transaction {
+	b.rlock()
+	defer b.mu.RUnlock()
+	var transactions []Transaction
+	collect := func(block Block) {
+		for _, tx := range block.transactions {
+			if tx.From == account || tx.To == account {
+				transactions = append(transactions, tx)
+			}
+		}
+	}
+	if b.accounts != nil {
+		for _, index := range b.accounts.blocks[account] {
+			block, err := b.store.Get(index)
+			if err != nil {
+				break
+			}
+			collect(block)
+		}
+		return transactions
+	}
+	for _, block := range b.blocks(1) {
+		collect(block)
+	}
+	return transactions
+}
+
+// This method returns every transaction between the two accounts, in chain order: those sent
+// from first to second and those sent from second to first, so the direction is not taken
+// into account; check each transaction's From field to tell them apart. If both accounts are
+// the same, the account's self-transfers are returned. The genesis block is skipped. If the
+// account index is enabled (see EnableAccountIndex), only the blocks holding the first
+// account's transactions are read.
+func (b *Blockchain) TransactionsBetween(first, second string) []Transaction {
+	var between []Transaction
+	for _, tx := range b.TransactionsFor(first) {
+		if (tx.From == first && tx.To == second) || (tx.From == second && tx.To == first) {
+			between = append(between, tx)
+		}
+	}
+	return between
+}
+
+// This method returns the blocks whose timestamp lies within [start, end], in chain order.
+// Both bounds are inclusive. The genesis block is included only if its timestamp is in the
+// range too. Because Validate guarantees that timestamps never decrease along the chain, the
+// walk stops at the first block after end.
+func (b *Blockchain) BlocksBetween(start, end time.Time) []Block {
+	b.rlock()
+	defer b.mu.RUnlock()
+	var blocks []Block
+	for _, block := range b.blocks(0) {
+		if block.timestamp.After(end) {
+			break
+		}
+		if !block.timestamp.Before(start) {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// This method returns an iterator over the index/block pairs of the chain, in order, starting
+// with the genesis block:
+//
+//	for i, block := range chain.All() { ... }
+//
+// The length of the chain is fixed under the read lock when iteration starts, and every block
+// is read under the read lock, so concurrent mining never shows the iterator a half-appended
+// block. Blocks added after iteration has started are not visited, and the loop body runs
+// without holding the lock. Iteration stops early if the block store fails.
+func (b *Blockchain) All() iter.Seq2[int, Block] {
+	return func(yield func(int, Block) bool) {
+		b.rlock()
+		n := b.store.Len()
+		b.mu.RUnlock()
+		for i := 0; i < n; i++ {
+			b.rlock()
+			block, err := b.store.Get(i)
+			b.mu.RUnlock()
+			if err != nil || !yield(i, block) {
+				return
+			}
+		}
+	}
+}
+
+// This method reports whether the transaction has been committed to the chain, and if so the
+// index of the block containing it. Transactions are matched by their hash, which covers the
+// parties, the amount, the timestamp and any signature. If the same transaction appears in
+// several blocks, the earliest one is returned.
+func (b *Blockchain) ContainsTransaction(tx Transaction) (blockIndex int, found bool) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	target := tx.Hash()
+	for i, block := range b.blocks(0) {
+		for _, candidate := range block.transactions {
+			if candidate.Hash() == target {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// This method returns the block on the main chain with the given hash, and whether it was
+// found. Blocks on side branches are not found.
+func (b *Blockchain) FindBlockByHash(hash string) (Block, bool) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	index := b.indexOf(hash)
+	if index < 0 {
+		return Block{}, false
+	}
+	block, err := b.store.Get(index)
+	return block, err == nil
+}
+
+// This method returns the most recent block on the main chain holding a transaction in which
+// the account is either the "from" or the "to" party, and whether there is one, for example
+// to show when a wallet was last seen. The chain is scanned backwards from the tip, stopping
+// at the first match; if the account index is enabled (see EnableAccountIndex), the block is
+// looked up directly. The genesis block is skipped, like in TransactionsFor.
+func (b *Blockchain) LastActivity(account string) (Block, bool) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	if b.accounts != nil {
+		indices := b.accounts.blocks[account]
+		if len(indices) == 0 {
+			return Block{}, false
+		}
+		block, err := b.store.Get(indices[len(indices)-1])
+		return block, err == nil
+	}
+	for i := b.store.Len() - 1; i >= 1; i-- {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return Block{}, false
+		}
+		for _, tx := range block.transactions {
+			if tx.From == account || tx.To == account {
+				return block, true
+			}
+		}
+	}
+	return Block{}, false
+}
+
+// This method returns how many blocks have been mined on top of the block with the given
+// hash: 0 if it is the tip, 6 once six more blocks follow it, and so on. This lets a payment
+// processor wait for a number of confirmations before trusting a transaction. An error is
+// returned if no block on the main chain has the hash.
+func (b *Blockchain) Confirmations(blockHash string) (int, error) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	index := b.indexOf(blockHash)
+	if index < 0 {
+		return 0, fmt.Errorf("%w: block %s is not on the chain", ErrBlockNotFound, shortHash(blockHash))
+	}
+	return b.store.Len() - 1 - index, nil
+}
+
+// This method returns the blocks at the indices in [from, to), in chain order, so that a
+// syncing node can request exactly the blocks it is missing, for example those after its tip.
+// An empty range (from == to) yields no blocks. An error is returned if from is negative, to
+// is beyond the length of the chain, or the bounds are inverted.
+func (b *Blockchain) GetRange(from, to int) ([]Block, error) {
+	b.rlock()
+	defer b.mu.RUnlock()
+	if from < 0 || to > b.store.Len() {
+		return nil, fmt.Errorf("%w: block range [%d, %d) out of range [0, %d)", ErrBlockNotFound, from, to, b.store.Len())
+	}
+	if from > to {
+		return nil, fmt.Errorf("block range [%d, %d) is inverted", from, to)
+	}
+	blocks := make([]Block, 0, to-from)
+	for i := from; i < to; i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,56 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+)
+
+// This method re-mines the chain's history onto a fresh blockchain at the given difficulty, for
+// example to migrate to a new difficulty or to drop a target threshold. The new chain starts
+// with an identical genesis block, so genesis data and allocations are preserved, and then
+// holds one block for every block of the source, with the same transactions (including
+// coinbase transactions), data and timestamps, but freshly mined nonces and hashes. The
+// mining reward, miner address, clock skew, retargeting and hash algorithm settings are
+// carried over, and so is the minimum difficulty, lowered to the new difficulty if it is
+// higher; the mempool, side branches, callbacks and finality are not.
+// An error is returned if the difficulty is out of range, if the block store fails, or if
+// mining fails.
+func (b *Blockchain) ReplayOnto(newDifficulty int) (Blockchain, error) {
+	b.rlock()
+	if limit := b.maxDifficulty(); newDifficulty < 0 || newDifficulty > limit {
+		b.mu.RUnlock()
+		return Blockchain{}, fmt.Errorf("difficulty %d out of range [0, %d]", newDifficulty, limit)
+	}
+	blocks, err := b.collect()
+	newHash := b.hashFunc()
+	replayed := createBlockchain(newDifficulty, b.genesisBlock.data, b.genesisBlock.timestamp, newHash)
+	replayed.hasher = b.hasher
+	replayed.miningReward = b.miningReward
+	replayed.minerAddress = b.minerAddress
+	replayed.maxClockSkew = b.maxClockSkew
+	replayed.targetBlockTime = b.targetBlockTime
+	replayed.retargetWindow = b.retargetWindow
+	replayed.minDifficulty = min(b.minDifficulty, newDifficulty)
+	b.mu.RUnlock()
+	if err != nil {
+		return Blockchain{}, err
+	}
+	previousBlock := replayed.genesisBlock
+	for i, source := range blocks[1:] {
+		block := Block{
+			transactions: source.Transactions(),
+			previousHash: previousBlock.hash,
+			timestamp:    source.timestamp,
+			merkleRoot:   merkleRoot(source.transactions),
+			height:       previousBlock.height + 1,
+			difficulty:   newDifficulty,
+		}
+		block.setData(copyData(source.data))
+		if err := block.mine(context.Background(), newHash); err != nil {
+			return Blockchain{}, fmt.Errorf("replay block %d: %w", i+1, err)
+		}
+		replayed.store.Append(block)
+		previousBlock = block
+	}
+	return replayed, nil
+}
@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// This function generates a new ECDSA key pair on the P-256 curve for signing transactions.
+func GenerateKeyPair() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// This function derives the account address that belongs to a public key: the hex-encoded
+// SHA-256 hash of the key's DER encoding. A signed transaction is only valid if its "from"
+// party is the address of the key that signed it, and a transaction from an account that
+// looks like such an address is only valid if it is signed.
+func AddressOf(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(der)), nil
+}
+
+// This function reports whether the account has the form of a key-derived address (see
+// AddressOf): 64 lowercase hex digits. Whether a key exists for it can't be told, so every
+// such account is treated as one.
+func isKeyAddress(account string) bool {
+	if len(account) != 2*sha256.Size {
+		return false
+	}
+	for _, c := range account {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// This method signs the transaction with the private key, storing the signature and the
+// public key on the transaction. The transaction's "from" party must be the address of the
+// key (see AddressOf). A transaction without a timestamp is stamped with the current time
+// first, because the timestamp is covered by the signature.
+func (tx *Transaction) Sign(key *ecdsa.PrivateKey) error {
+	address, err := AddressOf(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if tx.From != address {
+		return fmt.Errorf("sign transaction: sender %q is not the address of the signing key", tx.From)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if tx.Timestamp.IsZero() {
+		tx.Timestamp = time.Now()
+	}
+	tx.PublicKey = der
+	digest := tx.signingHash()
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return err
+	}
+	tx.Signature = signature
+	return nil
+}
+
+// This method reports whether the transaction carries a signature.
+func (tx Transaction) IsSigned() bool {
+	return len(tx.Signature) > 0 || len(tx.PublicKey) > 0
+}
+
+// This method reports whether the transaction's signature must be verified: if it carries
+// one, or if it is sent from a key-derived address, which only its key may spend from.
+// Transactions between accounts of other forms, such as names, may be unsigned.
+func (tx Transaction) needsSignature() bool {
+	return tx.IsSigned() || isKeyAddress(tx.From)
+}
+
+// This method checks that the transaction's signature was made by the key it carries, and
+// that the key belongs to the transaction's "from" party.
+func (tx Transaction) VerifySignature() error {
+	if !tx.IsSigned() {
+		return errors.New("invalid signature: transaction is not signed")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(tx.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	pub, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("invalid signature: public key is not an ECDSA key")
+	}
+	address, err := AddressOf(pub)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if tx.From != address {
+		return fmt.Errorf("invalid signature: sender %q is not the address of the signing key", tx.From)
+	}
+	digest := tx.signingHash()
+	if !ecdsa.VerifyASN1(pub, digest[:], tx.Signature) {
+		return errors.New("invalid signature: verification failed")
+	}
+	return nil
+}
+
+// This method returns the digest that is signed: the transaction without its signature.
+func (tx Transaction) signingHash() [sha256.Size]byte {
+	tx.Signature = nil
+	tx.Timestamp = tx.Timestamp.UTC()
+	data, _ := json.Marshal(tx)
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,32 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnsignedSpendFromKeyAddressIsRejected(t *testing.T) {
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := CreateBlockchainWithAllocations(1, map[string]float64{wallet.Address(): 100})
+	if err := b.AddTransaction(wallet.Address(), "mallory", 10); !errors.Is(err, ErrInvalidTransaction) {
+		t.Fatalf("AddTransaction() of an unsigned spend = %v, want ErrInvalidTransaction", err)
+	}
+	if err := wallet.Send(&b, "bob", 10); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	if got := b.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+
+	// A block recording the spend without its signature is rejected as well.
+	tamper(t, &b, 1, func(block *Block) {
+		block.transactions[0].Signature = nil
+		block.transactions[0].PublicKey = nil
+	})
+	if err := b.Validate(); !errors.Is(err, ErrChainTampered) {
+		t.Fatalf("Validate() of an unsigned spend = %v, want ErrChainTampered", err)
+	}
+}
@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A cheap health snapshot of a blockchain, as returned by Stats.
+type ChainStats struct {
+	Blocks       int     // total number of blocks, including the genesis block
+	Transactions int     // total number of user transactions, excluding coinbase transactions
+	TotalAmount  float64 // total amount moved by user transactions
+	TotalRewards float64 // total mining rewards and collected fees paid out through coinbase transactions
+	TotalFees    float64 // total fees paid by user transactions
+	AverageNonce float64 // average proof of work of the mined blocks, a proxy for mining effort
+}
+
+// This method computes statistics about the chain in a single pass over its blocks.
+// The amounts are summed exactly as Money.
+func (b *Blockchain) Stats() ChainStats {
+	b.rlock()
+	defer b.mu.RUnlock()
+	stats := ChainStats{Blocks: b.store.Len()}
+	var totalNonce int
+	var totalAmount, totalRewards, totalFees Money
+	for _, block := range b.blocks(1) {
+		totalNonce += block.pow
+		for _, tx := range block.transactions {
+			if tx.From == CoinbaseAddress {
+				totalRewards += moneyOf(tx.Amount)
+				continue
+			}
+			stats.Transactions++
+			totalAmount += moneyOf(tx.Amount)
+			totalFees += moneyOf(tx.Fee)
+		}
+	}
+	stats.TotalAmount = totalAmount.Float64()
+	stats.TotalRewards = totalRewards.Float64()
+	stats.TotalFees = totalFees.Float64()
+	if mined := b.store.Len() - 1; mined > 0 {
+		stats.AverageNonce = float64(totalNonce) / float64(mined)
+	}
+	return stats
+}
+
+// An account and its net balance, as returned by TopBalances.
+type AccountBalance struct {
+	Account string
+	Balance float64
+}
+
+// This method returns the n accounts with the highest net balance, highest first, computed in
+// a single pass over the chain. Ties are broken alphabetically by account, so the result is
+// deterministic. Genesis allocations and mining rewards count towards the balances, but
+// CoinbaseAddress itself is not listed. Fewer than n accounts are returned if the chain has
+// fewer, and none if n is not positive.
+func (b *Blockchain) TopBalances(n int) []AccountBalance {
+	if n <= 0 {
+		return nil
+	}
+	b.rlock()
+	balances := b.allocations()
+	for _, block := range b.blocks(1) {
+		for _, tx := range block.transactions {
+			applyTx(balances, tx)
+		}
+	}
+	b.mu.RUnlock()
+	accounts := make([]string, 0, len(balances))
+	for account := range balances {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if balances[accounts[i]] != balances[accounts[j]] {
+			return balances[accounts[i]] > balances[accounts[j]]
+		}
+		return accounts[i] < accounts[j]
+	})
+	if len(accounts) > n {
+		accounts = accounts[:n]
+	}
+	top := make([]AccountBalance, len(accounts))
+	for i, account := range accounts {
+		top[i] = AccountBalance{Account: account, Balance: balances[account].Float64()}
+	}
+	return top
+}
+
+// This method reports whether the ledger conserves value. See CheckConservation.
+func (b *Blockchain) ConservesValue() bool {
+	return b.CheckConservation() == nil
+}
+
+// This method checks that money is only moved between accounts, and only created by coinbase
+// transactions and genesis allocations: no account may end up with a negative balance, and the
+// sum of all positive balances must equal the allocations and coinbase payouts, minus the fees
+// paid. This catches ledgers that are inconsistent even though every hash recomputes
+// correctly, for example because an amount was tampered with and the chain re-mined. The
+// returned error names the offending account, or the mismatching totals.
+func (b *Blockchain) CheckConservation() error {
+	b.rlock()
+	defer b.mu.RUnlock()
+	balances := b.allocations()
+	var issued Money
+	for _, amount := range balances {
+		issued += amount
+	}
+	for _, block := range b.blocks(1) {
+		for _, tx := range block.transactions {
+			if tx.From == CoinbaseAddress {
+				issued += moneyOf(tx.Amount)
+			} else {
+				// The fee leaves circulation here, and comes back if a coinbase collects it.
+				issued -= moneyOf(tx.Fee)
+			}
+			applyTx(balances, tx)
+		}
+	}
+	accounts := make([]string, 0, len(balances))
+	for account := range balances {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	var supply Money
+	for _, account := range accounts {
+		if balances[account] < 0 {
+			return fmt.Errorf("value not conserved: account %q has a negative balance of %s", account, balances[account])
+		}
+		supply += balances[account]
+	}
+	if supply != issued {
+		return fmt.Errorf("value not conserved: balances sum to %s, but %s was issued", supply, issued)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+)
+
+// The storage backend holding the blocks of a blockchain, in order, starting with the genesis
+// block. Stores are append-only; a Blockchain serializes all access to its store, so
+// implementations don't need to be safe for concurrent mutation.
+// Stores that also implement "Truncate(n int) error", like MemoryStore and FileStore, support
+// replacing blocks, which ReplaceChain needs.
+type BlockStore interface {
+	Append(block Block) error
+	Get(index int) (Block, error)
+	Len() int
+}
+
+// The optional extension of BlockStore that allows dropping blocks from the end of the store.
+type truncatableStore interface {
+	BlockStore
+	Truncate(n int) error
+}
+
+// The default BlockStore, which keeps all blocks in a slice in memory.
+type MemoryStore struct {
+	blocks []Block
+}
+
+// This function creates an empty in-memory block store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// This method appends a block to the store.
+func (s *MemoryStore) Append(block Block) error {
+	s.blocks = append(s.blocks, block)
+	return nil
+}
+
+// This method returns the block at the given index.
+func (s *MemoryStore) Get(index int) (Block, error) {
+	if index < 0 || index >= len(s.blocks) {
+		return Block{}, fmt.Errorf("%w: index %d out of range [0, %d]", ErrBlockNotFound, index, len(s.blocks)-1)
+	}
+	return s.blocks[index], nil
+}
+
+// This method returns the number of blocks in the store.
+func (s *MemoryStore) Len() int {
+	return len(s.blocks)
+}
+
+// This method keeps the first n blocks and drops the rest.
+func (s *MemoryStore) Truncate(n int) error {
+	if n < 0 || n > len(s.blocks) {
+		return fmt.Errorf("cannot truncate %d blocks to %d", len(s.blocks), n)
+	}
+	// Cap the slice so that later appends reallocate instead of overwriting blocks that a
+	// concurrent reader may still be looking at.
+	s.blocks = s.blocks[:n:n]
+	return nil
+}
+
+// A BlockStore backed by an append-only file of newline-delimited JSON, one block per line.
+// Only the byte offset of every block is kept in memory; blocks are read from disk on demand.
+type FileStore struct {
+	file    *os.File
+	offsets []int64 // the byte offset at which each block's line starts
+	size    int64   // the byte offset just past the last line
+}
+
+// This function opens the block store file at the given path, creating it if it doesn't exist.
+// Existing blocks are indexed but not validated; open the store with
+// CreateBlockchainWithStore to validate them.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{file: file}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				file.Close()
+				return nil, fmt.Errorf("block store %s: truncated line at offset %d", path, s.size)
+			}
+			s.offsets = append(s.offsets, s.size)
+			s.size += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// This method appends a block to the end of the file.
+func (s *FileStore) Append(block Block) error {
+	line, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := s.file.WriteAt(line, s.size); err != nil {
+		return err
+	}
+	s.offsets = append(s.offsets, s.size)
+	s.size += int64(len(line))
+	return nil
+}
+
+// This method reads the block at the given index from the file.
+func (s *FileStore) Get(index int) (Block, error) {
+	if index < 0 || index >= len(s.offsets) {
+		return Block{}, fmt.Errorf("%w: index %d out of range [0, %d]", ErrBlockNotFound, index, len(s.offsets)-1)
+	}
+	end := s.size
+	if index+1 < len(s.offsets) {
+		end = s.offsets[index+1]
+	}
+	line := make([]byte, end-s.offsets[index])
+	if _, err := s.file.ReadAt(line, s.offsets[index]); err != nil {
+		return Block{}, err
+	}
+	var block Block
+	if err := json.Unmarshal(line, &block); err != nil {
+		return Block{}, fmt.Errorf("block %d: %w", index, err)
+	}
+	return block, nil
+}
+
+// This method returns the number of blocks in the file.
+func (s *FileStore) Len() int {
+	return len(s.offsets)
+}
+
+// This method keeps the first n blocks and drops the rest from the file.
+func (s *FileStore) Truncate(n int) error {
+	if n < 0 || n > len(s.offsets) {
+		return fmt.Errorf("cannot truncate %d blocks to %d", len(s.offsets), n)
+	}
+	size := s.size
+	if n < len(s.offsets) {
+		size = s.offsets[n]
+	}
+	if err := s.file.Truncate(size); err != nil {
+		return err
+	}
+	s.offsets = s.offsets[:n:n]
+	s.size = size
+	return nil
+}
+
+// This method flushes the file to disk and closes it.
+func (s *FileStore) Close() error {
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// This method returns the most recently added block. The caller must hold the lock.
+func (b *Blockchain) tip() (Block, error) {
+	return b.store.Get(b.store.Len() - 1)
+}
+
+// This method returns an iterator over the blocks from the given index onward. Iteration
+// stops early if the store fails to return a block; Validate reports such failures.
+// The caller must hold the lock.
+func (b *Blockchain) blocks(from int) iter.Seq2[int, Block] {
+	return func(yield func(int, Block) bool) {
+		for i := from; i < b.store.Len(); i++ {
+			block, err := b.store.Get(i)
+			if err != nil || !yield(i, block) {
+				return
+			}
+		}
+	}
+}
+
+// This method reads all blocks from the store into a slice. The caller must hold the lock.
+func (b *Blockchain) collect() ([]Block, error) {
+	blocks := make([]Block, 0, b.store.Len())
+	for i := 0; i < b.store.Len(); i++ {
+		block, err := b.store.Get(i)
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// This method replaces the stored blocks with the given chain, keeping the prefix both have
+// in common. The store must support truncation. If appending the new blocks fails, the
+// displaced blocks are put back, so that the chain is never left shortened. The caller must
+// hold the lock.
+func (b *Blockchain) replaceBlocks(chain []Block) error {
+	store, ok := b.store.(truncatableStore)
+	if !ok {
+		return errors.New("block store does not support replacing blocks")
+	}
+	common := 0
+	for common < len(chain) && common < store.Len() {
+		block, err := store.Get(common)
+		if err != nil {
+			return err
+		}
+		if block.hash != chain[common].hash {
+			break
+		}
+		common++
+	}
+	displaced := make([]Block, 0, store.Len()-common)
+	for i := common; i < store.Len(); i++ {
+		block, err := store.Get(i)
+		if err != nil {
+			return err
+		}
+		displaced = append(displaced, block)
+	}
+	if err := store.Truncate(common); err != nil {
+		return err
+	}
+	// Rebuild the balance index even if appending fails, since the chain may have changed anyway.
+	defer b.rebuildIndex()
+	if err := appendBlocks(store, chain[common:]); err != nil {
+		restoreErr := store.Truncate(common)
+		if restoreErr == nil {
+			restoreErr = appendBlocks(store, displaced)
+		}
+		if restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("restore displaced blocks: %w", restoreErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// This function appends the blocks to the store in order, stopping at the first failure.
+func appendBlocks(store BlockStore, blocks []Block) error {
+	for _, block := range blocks {
+		if err := store.Append(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// A MemoryStore that fails one append after a given number of them, to simulate a transient
+// I/O error of a FileStore.
+type flakyStore struct {
+	*MemoryStore
+	appends int // how many more appends succeed before one fails; negative means all of them
+}
+
+func (s *flakyStore) Append(block Block) error {
+	if s.appends == 0 {
+		s.appends = -1
+		return errors.New("disk full")
+	}
+	if s.appends > 0 {
+		s.appends--
+	}
+	return s.MemoryStore.Append(block)
+}
+
+func TestReplaceChainRestoresBlocksOnFailedAppend(t *testing.T) {
+	store := &flakyStore{MemoryStore: NewMemoryStore(), appends: -1}
+	b, err := CreateBlockchainWithStore(1, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 1})
+	mineTransactions(t, &b, Transaction{From: "alice", To: "carol", Amount: 1})
+	candidate := b.Clone()
+	if err := candidate.TruncateAfter(1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		mineTransactions(t, &candidate, Transaction{From: "alice", To: "dave", Amount: float64(i + 1)})
+	}
+	before := b.Blocks()
+	store.appends = 1
+	if replaced, err := b.ReplaceChain(candidate.Blocks()); replaced || err == nil {
+		t.Fatalf("ReplaceChain() = %v, %v, want a failure", replaced, err)
+	}
+	after := b.Blocks()
+	if len(after) != len(before) {
+		t.Fatalf("chain has %d blocks after the failed replacement, want %d", len(after), len(before))
+	}
+	for i := range before {
+		if after[i].Hash() != before[i].Hash() {
+			t.Fatalf("block %d changed after the failed replacement", i)
+		}
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.BalanceOf("carol"); got != 1 {
+		t.Fatalf("BalanceOf(carol) = %v, want 1", got)
+	}
+}
+
+func TestReopenRewardedChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CreateBlockchainWithStore(1, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetMiningReward(10)
+	b.SetMinerAddress("miner")
+	if _, err := b.MineEmptyBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var streamed bytes.Buffer
+	if err := b.SaveStreaming(&streamed); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := CreateBlockchainWithStore(1, store); err == nil {
+		t.Error("CreateBlockchainWithStore() accepted a coinbase paying more than no reward")
+	}
+	reopened, err := CreateBlockchainWithStoreAndReward(1, store, 10)
+	if err != nil {
+		t.Fatalf("CreateBlockchainWithStoreAndReward() = %v", err)
+	}
+	if got := reopened.BalanceOf("miner"); got != 10 {
+		t.Errorf("BalanceOf(miner) after reopening = %v, want 10", got)
+	}
+
+	if _, err := LoadStreaming(bytes.NewReader(streamed.Bytes())); err == nil {
+		t.Error("LoadStreaming() accepted a coinbase paying more than no reward")
+	}
+	loaded, err := LoadStreamingWithReward(bytes.NewReader(streamed.Bytes()), 10)
+	if err != nil {
+		t.Fatalf("LoadStreamingWithReward() = %v", err)
+	}
+	if got := loaded.BalanceOf("miner"); got != 10 {
+		t.Errorf("BalanceOf(miner) after streaming = %v, want 10", got)
+	}
+}
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The largest frame SyncWith accepts from a peer, to bound memory use on hostile input.
+const maxFrameSize = 256 << 20
+
+// The message exchanged by SyncWith.
+type chainMessage struct {
+	Blocks []Block `json:"blocks"`
+}
+
+// This method exchanges chains with a peer over an arbitrary connection, such as a net.Conn or
+// one end of a net.Pipe. It sends our chain while reading the peer's, and then adopts the
+// peer's chain following the ReplaceChain rules if it represents more work (see TotalWork). Both nodes are expected
+// to call SyncWith on their end of the connection.
+// Messages are framed with a 4-byte big-endian length prefix followed by a JSON payload, so
+// that several messages can be exchanged on one connection.
+// Receiving a chain with no more work than ours is not an error; receiving a heavier chain
+// that is invalid or doesn't share our genesis block is.
+// If the peer's message can't be read, SyncWith returns without waiting for our chain to be
+// sent: sending stops after the chunk being written, and the connection is closed if it
+// implements io.Closer, since it can't be used for further messages.
+func (b *Blockchain) SyncWith(peer io.ReadWriter) error {
+	ours := chainMessage{Blocks: b.Blocks()}
+	// Write concurrently with reading, so that two peers on a synchronous connection don't
+	// block each other by both writing first.
+	sent := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		sent <- writeFrame(peer, ours, done)
+	}()
+	var theirs chainMessage
+	if err := readFrame(peer, &theirs); err != nil {
+		// A peer whose message can't be read may never read ours either, and the writer would
+		// block forever on a synchronous connection. Closing the connection unblocks it, and
+		// the done channel stops it at the next chunk on connections that can't be closed.
+		close(done)
+		if closer, ok := peer.(io.Closer); ok {
+			closer.Close()
+			<-sent
+		}
+		return fmt.Errorf("receive chain: %w", err)
+	}
+	if err := <-sent; err != nil {
+		return fmt.Errorf("send chain: %w", err)
+	}
+	if totalWork(theirs.Blocks).Cmp(b.TotalWork()) <= 0 {
+		return nil
+	}
+	if _, err := b.ReplaceChain(theirs.Blocks); err != nil {
+		return fmt.Errorf("adopt peer chain: %w", err)
+	}
+	return nil
+}
+
+// The size of the chunks writeFrame writes a frame in.
+const frameChunkSize = 32 << 10
+
+// This function writes v as a length-prefixed JSON frame, in chunks of frameChunkSize bytes.
+// It gives up before the next chunk once done is closed.
+func writeFrame(w io.Writer, v interface{}, done <-chan struct{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(payload)), uint32(len(payload)))
+	frame = append(frame, payload...)
+	for len(frame) > 0 {
+		select {
+		case <-done:
+			return errors.New("sending abandoned")
+		default:
+		}
+		n := min(len(frame), frameChunkSize)
+		if _, err := w.Write(frame[:n]); err != nil {
+			return err
+		}
+		frame = frame[n:]
+	}
+	return nil
+}
+
+// This function reads a length-prefixed JSON frame into v.
+func readFrame(r io.Reader, v interface{}) error {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds the limit of %d", size, maxFrameSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncWith(t *testing.T) {
+	ours := CreateBlockchainWithGenesis(1, nil, GenesisEpoch())
+	theirs := CreateBlockchainWithGenesis(1, nil, GenesisEpoch())
+	mineTransactions(t, &theirs, Transaction{From: "alice", To: "bob", Amount: 1})
+	a, c := net.Pipe()
+	defer a.Close()
+	defer c.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- theirs.SyncWith(c)
+	}()
+	if err := ours.SyncWith(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ours.LatestBlock().Hash() != theirs.LatestBlock().Hash() {
+		t.Fatal("SyncWith() didn't adopt the heavier chain")
+	}
+}
+
+func TestSyncWithReturnsOnReadError(t *testing.T) {
+	b := CreateBlockchain(1)
+	a, c := net.Pipe()
+	defer c.Close()
+	go func() {
+		// Announce a frame above the limit, and never read the chain sent back.
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], maxFrameSize+1)
+		c.Write(header[:])
+	}()
+	done := make(chan error, 1)
+	go func() {
+		done <- b.SyncWith(a)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("SyncWith() = nil, want an error for an oversized frame")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SyncWith() hangs after a read error")
+	}
+}
+
+// A peer that can't be closed, whose chain can't be read once we start sending ours, and
+// whose first write blocks until release is closed. Every write is reported on writes.
+type stuckPeer struct {
+	writes  chan int
+	release chan struct{}
+}
+
+func (p *stuckPeer) Read([]byte) (int, error) {
+	<-p.writes
+	return 0, errors.New("connection reset")
+}
+
+func (p *stuckPeer) Write(data []byte) (int, error) {
+	p.writes <- len(data)
+	<-p.release
+	return len(data), nil
+}
+
+func TestSyncWithStopsSendingOnReadError(t *testing.T) {
+	b := CreateBlockchain(1)
+	// A payload of several chunks, so that sending takes more than one write.
+	if _, err := b.AddData(map[string]interface{}{"document": strings.Repeat("x", 4*frameChunkSize)}); err != nil {
+		t.Fatal(err)
+	}
+	peer := &stuckPeer{writes: make(chan int, 10), release: make(chan struct{})}
+	if err := b.SyncWith(peer); err == nil {
+		t.Fatal("SyncWith() = nil, want the read error")
+	}
+	close(peer.release)
+	select {
+	case <-peer.writes:
+		t.Fatal("SyncWith() kept sending after the read error")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
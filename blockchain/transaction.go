@@ -0,0 +1,255 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// A single transfer of an amount from one account to another.
+// A transaction may be signed with Sign, in which case Validate verifies the signature against
+// the "from" party. Unsigned transactions, as recorded by AddTransaction, are still accepted
+// between named accounts, but not from key-derived addresses (see AddressOf), which only
+// the address's key may spend from.
+// A transaction is identified by its ID, and the same transaction can't be recorded twice; set
+// Nonce to tell apart transactions that are otherwise identical.
+type Transaction struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Amount    float64   `json:"amount"`
+	Timestamp time.Time `json:"timestamp"`
+	Fee       float64   `json:"fee,omitempty"`       // paid by the sender on top of the amount, and collected by the miner
+	Nonce     uint64    `json:"nonce,omitempty"`     // distinguishes otherwise identical transactions
+	PublicKey []byte    `json:"publicKey,omitempty"` // DER encoding of the signer's public key
+	Signature []byte    `json:"signature,omitempty"` // ASN.1 ECDSA signature over the transaction
+}
+
+// This method returns the SHA-256 hash of the transaction, which identifies it in the
+// block's Merkle tree. The timestamp is hashed in UTC so that the hash survives a round trip
+// through JSON.
+func (tx Transaction) Hash() string {
+	tx.Timestamp = tx.Timestamp.UTC()
+	data, _ := json.Marshal(tx)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// This method checks that the fee is a non-negative amount of Money.
+func (tx Transaction) validateFee() error {
+	fee, err := ToMoney(tx.Fee)
+	if err != nil {
+		return fmt.Errorf("fee: %w", err)
+	}
+	if fee < 0 {
+		return fmt.Errorf("fee %v is negative", tx.Fee)
+	}
+	return nil
+}
+
+// This method returns what the transaction costs its sender: the amount plus the fee.
+func (tx Transaction) debit() Money {
+	return moneyOf(tx.Amount) + moneyOf(tx.Fee)
+}
+
+// This method returns the unique identifier of the transaction: the hex SHA-256 hash of
+// everything but its signature. Unlike Hash, it stays the same when a transaction is signed
+// again, so a replayed transaction is recognized even when it carries a fresh signature.
+func (tx Transaction) ID() string {
+	return fmt.Sprintf("%x", tx.signingHash())
+}
+
+// This method returns a copy of the transactions recorded in the block: the user
+// transactions, followed by the coinbase transaction if the block paid a mining reward.
+func (b Block) Transactions() []Transaction {
+	if b.transactions == nil {
+		return nil
+	}
+	transactions := make([]Transaction, len(b.transactions))
+	copy(transactions, b.transactions)
+	return transactions
+}
+
+// This method checks the transaction at the API boundary, so that garbage never makes it
+// onto the chain.
+func (tx Transaction) validate() error {
+	if err := tx.validateFields(); err != nil {
+		return err
+	}
+	if tx.needsSignature() {
+		if err := tx.VerifySignature(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+		}
+	}
+	return nil
+}
+
+// This method performs the checks of validate but the signature check: the parties, the
+// amount and the fee.
+func (tx Transaction) validateFields() error {
+	if tx.From == "" {
+		return fmt.Errorf("%w: sender is empty", ErrInvalidTransaction)
+	}
+	if tx.To == "" {
+		return fmt.Errorf("%w: recipient is empty", ErrInvalidTransaction)
+	}
+	if tx.From == CoinbaseAddress {
+		return fmt.Errorf("%w: sender %q is reserved for mining rewards", ErrInvalidTransaction, CoinbaseAddress)
+	}
+	if math.IsNaN(tx.Amount) || math.IsInf(tx.Amount, 0) {
+		return fmt.Errorf("%w: amount %v is not a finite number", ErrInvalidTransaction, tx.Amount)
+	}
+	if tx.Amount < 0 {
+		return fmt.Errorf("%w: amount %v is negative", ErrInvalidTransaction, tx.Amount)
+	}
+	if _, err := ToMoney(tx.Amount); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
+	if err := tx.validateFee(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
+	return nil
+}
+
+// This method enables or disables strict mode. In strict mode, transactions that are almost
+// always a mistake or spam are rejected on submission: transactions of a zero amount, and
+// self-transfers whose sender and recipient are the same. Negative amounts are rejected in
+// either mode. Strict mode is off by default, and doesn't affect blocks already on the chain.
+func (b *Blockchain) SetStrictMode(strict bool) {
+	b.lock()
+	defer b.mu.Unlock()
+	b.strict = strict
+}
+
+// This method returns an error if the already validated transaction may not be recorded:
+// if strict mode rejects it, if it is already queued or on the chain, or, if requireFunds is
+// set, if its sender can't afford it. The caller must hold the lock.
+func (b *Blockchain) checkAdmissible(tx Transaction, requireFunds bool) error {
+	if err := b.checkStrict(tx); err != nil {
+		return err
+	}
+	if err := b.checkNotRecorded(tx); err != nil {
+		return err
+	}
+	if requireFunds {
+		if spendable := b.spendable(tx.From); tx.debit() > spendable {
+			return fmt.Errorf("%w: %q can spend %s, but the transaction costs %s", ErrInsufficientFunds, tx.From, spendable, tx.debit())
+		}
+	}
+	return nil
+}
+
+// This method returns an error if strict mode is enabled and rejects the transaction. The
+// caller must hold the lock.
+func (b *Blockchain) checkStrict(tx Transaction) error {
+	if !b.strict {
+		return nil
+	}
+	if tx.Amount <= 0 {
+		return fmt.Errorf("%w: amount %v is not positive (strict mode)", ErrInvalidTransaction, tx.Amount)
+	}
+	if tx.From == tx.To {
+		return fmt.Errorf("%w: sender and recipient are both %q (strict mode)", ErrInvalidTransaction, tx.From)
+	}
+	return nil
+}
+
+// This method returns an error if a transaction with the same ID as tx is already on the
+// chain or waiting in the mempool, which would make recording tx a replay. The caller must
+// hold the lock.
+func (b *Blockchain) checkNotRecorded(tx Transaction) error {
+	id := tx.ID()
+	for _, pending := range b.pending {
+		if pending.ID() == id {
+			return fmt.Errorf("%w %s: already in the mempool", ErrDuplicateTransaction, shortHash(id))
+		}
+	}
+	return b.checkNotOnChain(id)
+}
+
+// This method returns an error if a user transaction with the given ID is already on the
+// chain. The caller must hold the exclusive lock.
+func (b *Blockchain) checkNotOnChain(id string) error {
+	if i, ok := b.recordedIDs()[id]; ok {
+		return fmt.Errorf("%w %s: already in block %d", ErrDuplicateTransaction, shortHash(id), i)
+	}
+	return nil
+}
+
+// This method returns an error if a user transaction of block is already recorded on the
+// branch that block extends: the main chain up to parent if parent is on it, and otherwise
+// the side branch ending in parent and the main chain up to where that branch forks off. The
+// caller must hold the exclusive lock.
+func (b *Blockchain) checkNotOnBranch(parent, block Block) error {
+	ids := b.recordedIDs()
+	branch := make(map[string]int)
+	forkIndex := b.indexOf(parent.hash)
+	for hash := parent.hash; forkIndex < 0; forkIndex = b.indexOf(hash) {
+		side, ok := b.sideBlocks[hash]
+		if !ok {
+			break
+		}
+		recordIDs(branch, side.height, side)
+		hash = side.previousHash
+	}
+	for _, tx := range block.transactions {
+		if tx.From == CoinbaseAddress {
+			continue
+		}
+		id := tx.ID()
+		if i, ok := ids[id]; ok && i <= forkIndex {
+			return fmt.Errorf("%w %s: already in block %d", ErrDuplicateTransaction, shortHash(id), i)
+		}
+		if height, ok := branch[id]; ok {
+			return fmt.Errorf("%w %s: already in side-branch block %d", ErrDuplicateTransaction, shortHash(id), height)
+		}
+	}
+	return nil
+}
+
+// This method returns the index of the transaction IDs on the chain, building it first if
+// needed. The caller must hold the exclusive lock.
+func (b *Blockchain) recordedIDs() map[string]int {
+	if b.txIDs == nil {
+		b.txIDs = make(map[string]int)
+		for i, block := range b.blocks(1) {
+			recordIDs(b.txIDs, i, block)
+		}
+	}
+	return b.txIDs
+}
+
+// This function records the IDs of the user transactions of the block at the given index in
+// seen, and returns an error naming the first of them that seen already holds. IDs already
+// in seen keep their index.
+func recordIDs(seen map[string]int, index int, block Block) error {
+	var first error
+	for _, tx := range block.transactions {
+		if tx.From == CoinbaseAddress {
+			continue
+		}
+		id := tx.ID()
+		if i, ok := seen[id]; ok {
+			if first == nil {
+				first = fmt.Errorf("%w %s: already in block %d", ErrDuplicateTransaction, shortHash(id), i)
+			}
+			continue
+		}
+		seen[id] = index
+	}
+	return first
+}
+
+// This function returns the ID of the first transaction that appears more than once in
+// transactions. The boolean is false if every transaction is unique.
+func duplicateTransaction(transactions []Transaction) (string, bool) {
+	seen := make(map[string]bool, len(transactions))
+	for _, tx := range transactions {
+		id := tx.ID()
+		if seen[id] {
+			return id, true
+		}
+		seen[id] = true
+	}
+	return "", false
+}
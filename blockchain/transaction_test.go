@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSubmitTransactionTwice(t *testing.T) {
+	b := CreateBlockchain(1)
+	tx := Transaction{From: "alice", To: "bob", Amount: 5, Timestamp: time.Unix(1, 0)}
+	if err := b.SubmitTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SubmitTransaction(tx); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("second SubmitTransaction() = %v, want ErrDuplicateTransaction", err)
+	}
+	if err := b.QueueTransaction(tx); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("QueueTransaction() = %v, want ErrDuplicateTransaction", err)
+	}
+	if got := b.BalanceOf("bob"); got != 5 {
+		t.Fatalf("BalanceOf(bob) = %v, want 5", got)
+	}
+}
+
+func TestQueueTransactionTwice(t *testing.T) {
+	b := CreateBlockchain(1)
+	tx := Transaction{From: "alice", To: "bob", Amount: 5, Timestamp: time.Unix(1, 0)}
+	if err := b.QueueTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.QueueTransaction(tx); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("second QueueTransaction() = %v, want ErrDuplicateTransaction", err)
+	}
+}
+
+func TestAddBlockRejectsReplayedTransaction(t *testing.T) {
+	b := CreateBlockchain(1)
+	recorded := Transaction{From: "alice", To: "bob", Amount: 5, Timestamp: time.Unix(1, 0)}
+	mineTransactions(t, &b, recorded)
+	block := peerBlock(t, &b, func(block *Block) {
+		block.transactions = []Transaction{recorded}
+	})
+	if err := b.AddBlock(block); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("AddBlock() = %v, want ErrDuplicateTransaction", err)
+	}
+	if err := b.AppendMinedBlock(block); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("AppendMinedBlock() = %v, want ErrDuplicateTransaction", err)
+	}
+	b.store.Append(block)
+	if err := b.Validate(); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("Validate() = %v, want ErrDuplicateTransaction", err)
+	}
+	if diagnostics := b.Diagnose(); len(diagnostics) != 1 || diagnostics[0].Problems[0] != ProblemDuplicateTx {
+		t.Fatalf("Diagnose() = %v, want a duplicate transaction", diagnostics)
+	}
+}
+
+func TestAddBlockRejectsReplayOnSideBranch(t *testing.T) {
+	b := CreateBlockchain(1)
+	mineTransactions(t, &b, Transaction{From: "alice", To: "carol", Amount: 1})
+	fork := b.Clone()
+	mineTransactions(t, &b, Transaction{From: "alice", To: "dave", Amount: 1})
+	replayed := Transaction{From: "alice", To: "bob", Amount: 5, Timestamp: time.Unix(1, 0)}
+	first := mineTransactions(t, &fork, replayed)
+	if err := b.AddBlock(first); err != nil {
+		t.Fatal(err)
+	}
+	second := peerBlock(t, &fork, func(block *Block) {
+		block.transactions = []Transaction{replayed}
+	})
+	if err := b.AddBlock(second); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("AddBlock() = %v, want ErrDuplicateTransaction", err)
+	}
+}
+
+func TestLoadStreamingRejectsReplayedTransaction(t *testing.T) {
+	b := CreateBlockchain(1)
+	recorded := Transaction{From: "alice", To: "bob", Amount: 5, Timestamp: time.Unix(1, 0)}
+	mineTransactions(t, &b, recorded)
+	b.store.Append(peerBlock(t, &b, func(block *Block) {
+		block.transactions = []Transaction{recorded}
+	}))
+	var buf bytes.Buffer
+	if err := b.SaveStreaming(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStreaming(&buf); !errors.Is(err, ErrDuplicateTransaction) {
+		t.Fatalf("LoadStreaming() = %v, want ErrDuplicateTransaction", err)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		name               string
+		from, to           string
+		amount             float64
+		permissive, strict bool // whether the transaction is accepted in each mode
+	}{
+		{"zero amount", "alice", "bob", 0, true, false},
+		{"negative amount", "alice", "bob", -1, false, false},
+		{"self-transfer", "alice", "alice", 1, true, false},
+		{"ordinary", "alice", "bob", 1, true, true},
+	}
+	for _, test := range tests {
+		for _, strict := range []bool{false, true} {
+			want := test.permissive
+			if strict {
+				want = test.strict
+			}
+			submit := map[string]func(b *Blockchain) error{
+				"AddTransaction": func(b *Blockchain) error { return b.AddTransaction(test.from, test.to, test.amount) },
+				"QueueTransaction": func(b *Blockchain) error {
+					return b.QueueTransaction(Transaction{From: test.from, To: test.to, Amount: test.amount})
+				},
+			}
+			for method, submit := range submit {
+				t.Run(fmt.Sprintf("%s/strict=%v/%s", test.name, strict, method), func(t *testing.T) {
+					b := CreateBlockchain(1)
+					b.SetStrictMode(strict)
+					err := submit(&b)
+					if (err == nil) != want {
+						t.Fatalf("%s() = %v, want accepted %v", method, err, want)
+					}
+					if err != nil && !errors.Is(err, ErrInvalidTransaction) {
+						t.Fatalf("%s() = %v, want ErrInvalidTransaction", method, err)
+					}
+				})
+			}
+		}
+	}
+}
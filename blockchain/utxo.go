@@ -0,0 +1,78 @@
+package blockchain
+
+// An index of the unspent balance of every account, maintained incrementally as blocks are
+// added, so that balance queries don't have to walk the chain.
+type balanceIndex struct {
+	balances map[string]Money
+}
+
+// This method enables an index of every account's unspent balance, in the spirit of a UTXO
+// set, which makes BalanceOf and Wallet.Send take constant time instead of walking the chain.
+// The index is built from the existing chain, updated whenever a block is mined or added, and
+// rebuilt when ReplaceChain, AddBlock or TruncateAfter rewrite the chain. It costs memory for
+// every account that ever transacted. The index is not saved; a chain read back with
+// LoadFromFile or another loader starts without it, and the index must be enabled again.
+// Enabling an already enabled index has no effect.
+func (b *Blockchain) EnableUTXOIndex() {
+	b.lock()
+	defer b.mu.Unlock()
+	if b.utxo != nil {
+		return
+	}
+	b.utxo = &balanceIndex{}
+	b.rebuildIndex()
+}
+
+// This method rebuilds the balance index and the account index from the chain, if they are
+// enabled, and drops the index of transaction IDs, which is rebuilt when next needed. The
+// caller must hold the exclusive lock.
+func (b *Blockchain) rebuildIndex() {
+	b.txIDs = nil
+	if b.utxo == nil && b.accounts == nil {
+		return
+	}
+	if b.utxo != nil {
+		b.utxo.balances = b.allocations()
+	}
+	if b.accounts != nil {
+		b.accounts.blocks = make(map[string][]int)
+	}
+	for i, block := range b.blocks(1) {
+		b.utxo.add(block)
+		b.accounts.add(i, block)
+	}
+}
+
+// This method updates the balance index, the account index and the index of transaction
+// IDs, if they are built, with a block appended to the chain. The caller must hold the
+// exclusive lock.
+func (b *Blockchain) indexBlock(block Block) {
+	b.utxo.add(block)
+	b.accounts.add(b.store.Len()-1, block)
+	if b.txIDs != nil {
+		recordIDs(b.txIDs, b.store.Len()-1, block)
+	}
+}
+
+// This method applies the transactions of a block to the balances, like BalanceOf sums them.
+// Nothing happens if the index is nil.
+func (idx *balanceIndex) add(block Block) {
+	if idx == nil {
+		return
+	}
+	for _, tx := range block.transactions {
+		applyTx(idx.balances, tx)
+	}
+}
+
+// This method returns a copy of the index that shares no state with it, or nil.
+func (idx *balanceIndex) clone() *balanceIndex {
+	if idx == nil {
+		return nil
+	}
+	balances := make(map[string]Money, len(idx.balances))
+	for account, balance := range idx.balances {
+		balances[account] = balance
+	}
+	return &balanceIndex{balances: balances}
+}
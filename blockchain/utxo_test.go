@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// This function returns a chain of n blocks after the genesis block, mined at difficulty 0
+// with a frozen clock so that it builds quickly. Block i records one transfer of 1 between
+// the accounts the transfer function returns for it.
+func longChain(tb testing.TB, n int, transfer func(i int) (from, to string)) *Blockchain {
+	tb.Helper()
+	b := CreateBlockchainWithGenesis(0, nil, GenesisEpoch())
+	epoch := GenesisEpoch()
+	b.SetClock(func() time.Time { return epoch })
+	for i := 0; i < n; i++ {
+		from, to := transfer(i)
+		if err := b.SubmitTransaction(Transaction{From: from, To: to, Amount: 1, Timestamp: epoch, Nonce: uint64(i)}); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return &b
+}
+
+func TestUTXOIndexFollowsTheChain(t *testing.T) {
+	b := CreateBlockchain(1)
+	b.EnableUTXOIndex()
+	mineTransactions(t, &b, Transaction{From: "alice", To: "bob", Amount: 5})
+	mineTransactions(t, &b, Transaction{From: "bob", To: "carol", Amount: 2})
+	if got := b.BalanceOf("bob"); got != 3 {
+		t.Fatalf("BalanceOf(bob) after mining = %v, want 3", got)
+	}
+	if err := b.TruncateAfter(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.BalanceOf("bob"); got != 5 {
+		t.Fatalf("BalanceOf(bob) after TruncateAfter = %v, want 5", got)
+	}
+
+	longer := b.Clone()
+	mineTransactions(t, &longer, Transaction{From: "bob", To: "dave", Amount: 4})
+	mineTransactions(t, &longer, Transaction{From: "alice", To: "dave", Amount: 1})
+	if replaced, err := b.ReplaceChain(longer.Blocks()); !replaced {
+		t.Fatalf("ReplaceChain() = %v", err)
+	}
+	for account, want := range map[string]float64{"bob": 1, "dave": 5, "carol": 0} {
+		if got := b.BalanceOf(account); got != want {
+			t.Errorf("BalanceOf(%q) after ReplaceChain = %v, want %v", account, got, want)
+		}
+	}
+}
+
+func BenchmarkBalanceOf(b *testing.B) {
+	chain := longChain(b, 100000, func(i int) (string, string) {
+		return fmt.Sprintf("account%d", i%100), fmt.Sprintf("account%d", (i+1)%100)
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			chain.BalanceOf("account42")
+		}
+	})
+	chain.EnableUTXOIndex()
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			chain.BalanceOf("account42")
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+)
+
+// A Wallet holds an ECDSA key pair and sends signed transactions from the account that
+// belongs to it, so that applications don't have to assemble and sign transactions by hand.
+type Wallet struct {
+	key     *ecdsa.PrivateKey
+	address string
+}
+
+// This function creates a wallet with a freshly generated key pair (see GenerateKeyPair).
+func NewWallet() (*Wallet, error) {
+	key, err := GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return WalletFromKey(key)
+}
+
+// This function creates a wallet for an existing private key.
+func WalletFromKey(key *ecdsa.PrivateKey) (*Wallet, error) {
+	address, err := AddressOf(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{key: key, address: address}, nil
+}
+
+// This method returns the account address of the wallet, derived from its public key with
+// AddressOf.
+func (w *Wallet) Address() string {
+	return w.address
+}
+
+// This method returns the wallet's private key, for example to persist it.
+func (w *Wallet) PrivateKey() *ecdsa.PrivateKey {
+	return w.key
+}
+
+// This method constructs a transaction of the given amount from the wallet's address to the
+// recipient, signs it, and submits it to the chain, which mines a block holding it.
+// An error is returned, and nothing is appended, if the amount exceeds the wallet's balance
+// on the chain (minus what it already sends in queued transactions), or if the chain rejects
+// the transaction as in SubmitTransaction. The balance is checked under the chain's lock, so
+// concurrent sends can't overdraw the wallet.
+func (w *Wallet) Send(chain *Blockchain, to string, amount float64) error {
+	tx := Transaction{From: w.address, To: to, Amount: amount}
+	if err := tx.Sign(w.key); err != nil {
+		return err
+	}
+	_, err := chain.submitTransaction(context.Background(), tx, true)
+	return err
+}
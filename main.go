@@ -3,7 +3,8 @@ This is a simple blockchain application.
 
 1. Create a new blockchain instance
 2. Record some transactions on the blockchain
-3. Check if the blockchain is valid
+3. Print the blocks of the blockchain
+4. Check if the blockchain is valid
 
 In a real application, additional security measures, such as encryption and authentication,
 would be implemented to protect the data and prevent unauthorized access or tampering.
@@ -13,6 +14,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/Heidelberger/blockchain/blockchain"
 )
@@ -22,8 +24,17 @@ func main() {
 	myBlockchain := blockchain.CreateBlockchain(2)
 
 	// record some transactions on the blockchain
-	myBlockchain.AddTransaction("Alice", "Bob", 5)
-	myBlockchain.AddTransaction("John", "Bob", 2)
+	if err := myBlockchain.AddTransaction("Alice", "Bob", 5); err != nil {
+		log.Fatal(err)
+	}
+	block, err := myBlockchain.AddTransactionBlock("John", "Bob", 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("recorded in block", block.Hash())
+
+	// print the blocks of the blockchain
+	fmt.Print(myBlockchain)
 
 	// check if the blockchain is valid
 	fmt.Println(myBlockchain.IsValid())